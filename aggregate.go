@@ -0,0 +1,277 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// aggregate functions of AggregateRequest.Aggregates
+const (
+	AggregateCount = "count"
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+)
+
+// time buckets of AggregateRequest.Bucket
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+
+	// bucketParam the result field and orderBy param of the time bucket
+	bucketParam = "bucket"
+)
+
+type AggregateRequest struct {
+	PageRequest
+
+	// GroupBy comma separated group params , checked by WithGroupByCols
+	GroupBy string `json:"groupBy" query:"groupBy" form:"groupBy"`
+
+	// Aggregates comma separated fn or fn:param , fn is one of count sum avg min max ,
+	// params are checked by WithAggregateCols , e.g. "count,sum:amount". empty means count
+	Aggregates string `json:"aggregates" query:"aggregates" form:"aggregates"`
+
+	// Bucket group by the begin end column truncated to hour , day , week or month ,
+	// empty means no time bucket
+	Bucket string `json:"bucket" query:"bucket" form:"bucket" validate:"omitempty,oneof=hour day week month"`
+}
+
+// AggregateRow a group , keyed by the group params , bucket and aggregate names (fn or fn_param)
+type AggregateRow map[string]interface{}
+
+// WithGroupByCol allow param in AggregateRequest.GroupBy , grouping by column col (default same as param)
+func WithGroupByCol(param string, col ...string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		if len(col) > 0 {
+			ctx.groupCols[param] = col[0]
+			return
+		}
+		ctx.groupCols[param] = param
+	})
+}
+
+// WithGroupByCols allow all params in AggregateRequest.GroupBy , param -> column
+func WithGroupByCols(all map[string]string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.groupCols = all
+	})
+}
+
+// WithAggregateCol allow param in AggregateRequest.Aggregates , aggregating column col (default same as param)
+func WithAggregateCol(param string, col ...string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		if len(col) > 0 {
+			ctx.aggregateCols[param] = col[0]
+			return
+		}
+		ctx.aggregateCols[param] = param
+	})
+}
+
+// WithAggregateCols allow all params in AggregateRequest.Aggregates , param -> column
+func WithAggregateCols(all map[string]string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.aggregateCols = all
+	})
+}
+
+// aggregateCol an output column of the aggregate query
+type aggregateCol struct {
+	alias string
+	expr  string
+}
+
+// AggregateQuery group the rows of m matched by req (time window , key , filter and WithWhere conditions)
+// and page the groups , Total is the number of groups. rows are ordered by req.OrderBy ,
+// which takes group params , bucket and aggregate names , default by the groups ascending
+func AggregateQuery[T schema.Tabler](tx *gorm.DB, req AggregateRequest, m T,
+	opts ...PageOption) (*PageResponseOf[AggregateRow], error) {
+	ctx := newPageCtx(tx, opts...)
+	var err error
+	if req.PageRequest, err = ctx.limitPage(req.PageRequest); err != nil {
+		return nil, err
+	}
+	if err = ctx.applyConds(req.PageRequest, m); err != nil {
+		return nil, err
+	}
+	groups, err := ctx.parseGroups(req.GroupBy, req.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	aggregates, err := ctx.parseAggregates(req.Aggregates)
+	if err != nil {
+		return nil, err
+	}
+	selects := make([]string, 0, len(groups)+len(aggregates))
+	orderCols := make(map[string]string, len(groups)+len(aggregates))
+	for _, it := range append(groups, aggregates...) {
+		if _, dup := orderCols[it.alias]; dup {
+			return nil, errors.Errorf("groupBy and aggregate both named '%s'", it.alias)
+		}
+		quoted := quoteIdent(ctx.tx, it.alias)
+		selects = append(selects, fmt.Sprintf("%s AS %s", it.expr, quoted))
+		orderCols[it.alias] = quoted
+	}
+	groupQuery := ctx.tx.Model(m).Select(strings.Join(selects, ", "))
+	for _, it := range groups {
+		groupQuery = groupQuery.Group(it.expr)
+	}
+	orders, err := parseOrderBy(req.OrderBy, req.Reserve, orderCols)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		for _, it := range groups {
+			orders = append(orders, orderItem{param: it.alias, col: orderCols[it.alias], desc: req.Reserve})
+		}
+	}
+	var total int64
+	err = ctx.tx.Session(&gorm.Session{NewDB: true}).
+		Table("(?) AS t_aggregate", groupQuery.Session(&gorm.Session{})).
+		Count(&total).Error
+	if err != nil {
+		return nil, err
+	}
+	q := groupQuery.Session(&gorm.Session{}).
+		Offset(req.Offset()).
+		Limit(req.Limit())
+	for _, it := range orders {
+		q = q.Order(it.String())
+	}
+	rows := make([]map[string]interface{}, 0)
+	if err = q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	results := make([]AggregateRow, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, row)
+	}
+	return &PageResponseOf[AggregateRow]{
+		Total:       total,
+		Page:        req.PageV(),
+		PageSize:    req.PageSizeV(),
+		Results:     results,
+		CountMode:   CountExact,
+		MaxPageSize: ctx.limits.MaxPageSize,
+		MaxOffset:   ctx.limits.MaxOffset,
+	}, nil
+}
+
+// parseGroups the time bucket followed by the group params , each one must be in the whitelist
+func (ctx *pageCtx) parseGroups(groupBy string, bucket string) ([]aggregateCol, error) {
+	ret := make([]aggregateCol, 0)
+	if bucket != "" {
+		col, err := ctx.timeCol(ctx.schema)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := bucketExpr(ctx.tx, col, bucket)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, aggregateCol{alias: bucketParam, expr: expr})
+	}
+	seen := map[string]bool{bucketParam: bucket != ""}
+	for _, param := range strings.Split(groupBy, ",") {
+		param = strings.TrimSpace(param)
+		if len(param) == 0 {
+			continue
+		}
+		col, ok := ctx.groupCols[param]
+		if !ok || param == bucketParam {
+			return nil, errors.Errorf("groupBy '%s' not allowed , must be one of [%s]", param,
+				mkArrayString(ctx.groupCols))
+		}
+		if !_identRegexp.MatchString(param) {
+			return nil, errors.Errorf("invalid groupBy name '%s'", param)
+		}
+		if seen[param] {
+			return nil, errors.Errorf("duplicate groupBy '%s'", param)
+		}
+		seen[param] = true
+		ret = append(ret, aggregateCol{alias: param, expr: col})
+	}
+	return ret, nil
+}
+
+// parseAggregates parse fn or fn:param items , the alias is fn or fn_param
+func (ctx *pageCtx) parseAggregates(aggregates string) ([]aggregateCol, error) {
+	ret := make([]aggregateCol, 0)
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(aggregates, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		fn, param, hasParam := strings.Cut(item, ":")
+		fn = strings.ToLower(strings.TrimSpace(fn))
+		param = strings.TrimSpace(param)
+		switch fn {
+		case AggregateCount, AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+		default:
+			return nil, errors.Errorf("aggregate '%s' not allowed , must be one of [%s]", fn,
+				strings.Join([]string{AggregateCount, AggregateSum, AggregateAvg, AggregateMin, AggregateMax}, ","))
+		}
+		col := aggregateCol{alias: fn, expr: "COUNT(*)"}
+		if hasParam {
+			c, ok := ctx.aggregateCols[param]
+			if !ok {
+				return nil, errors.Errorf("aggregate '%s' of '%s' not allowed , must be one of [%s]", fn, param,
+					mkArrayString(ctx.aggregateCols))
+			}
+			col = aggregateCol{alias: fmt.Sprintf("%s_%s", fn, param), expr: fmt.Sprintf("%s(%s)", strings.ToUpper(fn), c)}
+		} else if fn != AggregateCount {
+			return nil, errors.Errorf("aggregate '%s' needs a field , e.g. '%s:amount'", fn, fn)
+		}
+		if !_identRegexp.MatchString(col.alias) {
+			return nil, errors.Errorf("invalid aggregate name '%s'", col.alias)
+		}
+		if seen[col.alias] {
+			return nil, errors.Errorf("duplicate aggregate '%s'", item)
+		}
+		seen[col.alias] = true
+		ret = append(ret, col)
+	}
+	if len(ret) == 0 {
+		ret = append(ret, aggregateCol{alias: AggregateCount, expr: "COUNT(*)"})
+	}
+	return ret, nil
+}
+
+// bucketExpr truncate col to the bucket , weeks start on monday
+func bucketExpr(tx *gorm.DB, col string, bucket string) (string, error) {
+	switch bucket {
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+	default:
+		return "", errors.Errorf("bucket '%s' not allowed , must be one of [%s]", bucket,
+			strings.Join([]string{BucketHour, BucketDay, BucketWeek, BucketMonth}, ","))
+	}
+	switch tx.Dialector.Name() {
+	case DsTypePg:
+		return fmt.Sprintf("date_trunc('%s', %s)", bucket, col), nil
+	case DsTypeSqlLite:
+		return map[string]string{
+			BucketHour:  fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00', %s)", col),
+			BucketDay:   fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s)", col),
+			BucketWeek:  fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s, 'weekday 0', '-6 days')", col),
+			BucketMonth: fmt.Sprintf("strftime('%%Y-%%m-01 00:00:00', %s)", col),
+		}[bucket], nil
+	default:
+		return "", errors.Errorf("time bucket not supported by '%s'", tx.Dialector.Name())
+	}
+}
+
+// quoteIdent quote name as an identifier of the dialect , so the case of aliases is kept
+func quoteIdent(tx *gorm.DB, name string) string {
+	var b strings.Builder
+	tx.Dialector.QuoteTo(&b, name)
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrMissingActor no actor in the statement context while SetActorRequired(true)
+var ErrMissingActor = errors.New("no actor in context")
+
+var _actorRequired atomic.Bool
+
+// SetActorRequired whether the audit mixins fail with ErrMissingActor when the context has no actor ,
+// otherwise the audit columns are left empty. read from the requireActor config of the default datasource
+func SetActorRequired(required bool) {
+	_actorRequired.Store(required)
+}
+
+// WithActor return a copy of ctx carrying the actor recorded by the audit mixins
+func WithActor(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, CtxActorKey, id)
+}
+
+// ActorFrom the actor carried by ctx
+func ActorFrom(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(CtxActorKey).(string)
+	return id, ok && id != ""
+}
+
+// actorOf the actor of the statement , ErrMissingActor when required and missing
+func actorOf(tx *gorm.DB) (string, error) {
+	id, ok := ActorFrom(tx.Statement.Context)
+	if !ok && _actorRequired.Load() {
+		return "", ErrMissingActor
+	}
+	return id, nil
+}
+
+// CreatedBy 创建人，创建时取上下文中的 Actor
+type CreatedBy struct {
+	CreatedBy string `gorm:"column:created_by;type:varchar(64)" json:"createdBy,omitempty"`
+}
+
+// UpdatedBy 更新人，创建和更新时取上下文中的 Actor
+type UpdatedBy struct {
+	UpdatedBy string `gorm:"column:updated_by;type:varchar(64)" json:"updatedBy,omitempty"`
+}
+
+// DeletedBy 删除人，软删除时取上下文中的 Actor ，与 DeletedAt 一起使用
+type DeletedBy struct {
+	DeletedBy string `gorm:"column:deleted_by;type:varchar(64)" json:"deletedBy,omitempty"`
+}
+
+// AuditedBy 创建人 + 更新人 + 删除人
+type AuditedBy struct {
+	CreatedBy
+	UpdatedBy
+	DeletedBy
+}
+
+var (
+	_createdByType = reflect.TypeOf(CreatedBy{})
+	_updatedByType = reflect.TypeOf(UpdatedBy{})
+	_deletedByType = reflect.TypeOf(DeletedBy{})
+)
+
+// auditField the column of s declared by the audit mixin of type mixin , nil when s does not embed it
+func auditField(s *schema.Schema, mixin reflect.Type) *schema.Field {
+	if s == nil {
+		return nil
+	}
+	for _, field := range s.Fields {
+		index := field.StructField.Index
+		if len(index) < 2 || field.DBName == "" {
+			continue
+		}
+		owner := s.ModelType.FieldByIndex(index[:len(index)-1]).Type
+		if owner.Kind() == reflect.Ptr {
+			owner = owner.Elem()
+		}
+		if owner == mixin {
+			return field
+		}
+	}
+	return nil
+}
+
+// softDeleteField the soft delete column of s , nil when rows are deleted for real
+func softDeleteField(s *schema.Schema) *schema.Field {
+	if s == nil {
+		return nil
+	}
+	for _, c := range s.DeleteClauses {
+		if sd, ok := c.(gorm.SoftDeleteDeleteClause); ok {
+			return sd.Field
+		}
+	}
+	return nil
+}
+
+// auditPlugin fill the columns of the audit mixins by callbacks rather than hooks , a hook of a
+// mixin is shadowed by the same hook of a shallower mixin (e.g. UuidPrimaryKey.BeforeCreate)
+type auditPlugin struct{}
+
+func (auditPlugin) Name() string {
+	return "kboot:audit"
+}
+
+func (auditPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().After("gorm:before_create").Before("gorm:save_before_associations").
+			Register("kboot:audit_create", auditCreate),
+		cb.Update().After("gorm:before_update").Before("gorm:save_before_associations").
+			Register("kboot:audit_update", auditUpdate),
+		cb.Delete().After("gorm:before_delete").Before("gorm:delete_before_associations").
+			Register("kboot:audit_delete", auditDelete),
+	)
+}
+
+// auditActor the actor of the statement when it has any of fields , ok is false when there is
+// nothing to record
+func auditActor(db *gorm.DB, fields ...*schema.Field) (string, bool) {
+	hasField := slices.ContainsFunc(fields, func(field *schema.Field) bool {
+		return field != nil
+	})
+	if db.Error != nil || db.Statement.SkipHooks || !hasField {
+		return "", false
+	}
+	actor, err := actorOf(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return "", false
+	}
+	return actor, actor != ""
+}
+
+func auditCreate(db *gorm.DB) {
+	stmt := db.Statement
+	createdBy, updatedBy := auditField(stmt.Schema, _createdByType), auditField(stmt.Schema, _updatedByType)
+	actor, ok := auditActor(db, createdBy, updatedBy)
+	if !ok {
+		return
+	}
+	eachModel(stmt.ReflectValue, func(v reflect.Value) {
+		if createdBy != nil {
+			// an explicit creator is kept
+			if _, zero := createdBy.ValueOf(stmt.Context, v); zero {
+				_ = db.AddError(createdBy.Set(stmt.Context, v, actor))
+			}
+		}
+		if updatedBy != nil {
+			_ = db.AddError(updatedBy.Set(stmt.Context, v, actor))
+		}
+	})
+}
+
+func auditUpdate(db *gorm.DB) {
+	updatedBy := auditField(db.Statement.Schema, _updatedByType)
+	actor, ok := auditActor(db, updatedBy)
+	if !ok {
+		return
+	}
+	// SetColumn covers Updates with a struct or a map , the column is selected when the update is
+	// restricted to selected columns (gorm writes UpdatedAt there too)
+	stmt := db.Statement
+	if len(stmt.Selects) > 0 && !slices.Contains(stmt.Selects, "*") {
+		stmt.Selects = append(slices.Clip(stmt.Selects), updatedBy.DBName)
+	}
+	stmt.SetColumn(updatedBy.DBName, actor, true)
+}
+
+func auditDelete(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt.Unscoped || softDeleteField(stmt.Schema) == nil {
+		return
+	}
+	deletedBy := auditField(stmt.Schema, _deletedByType)
+	actor, ok := auditActor(db, deletedBy)
+	if !ok {
+		return
+	}
+	stmt.SetColumn(deletedBy.DBName, actor, true)
+	// the soft delete clause replaces the SET clause by its deleted_at assignment when it builds the
+	// update , so deleted_by is appended while the clause is built
+	set := stmt.Clauses["SET"]
+	set.Name = "SET"
+	set.Builder = func(c clause.Clause, builder clause.Builder) {
+		if assignments, ok := c.Expression.(clause.Set); ok {
+			c.Expression = append(assignments, clause.Assignment{Column: clause.Column{Name: deletedBy.DBName}, Value: actor})
+		}
+		c.Builder = nil
+		c.Build(builder)
+	}
+	stmt.Clauses["SET"] = set
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type document struct {
+	UuidPriWithFullTsBase
+	AuditedBy
+	Title string `gorm:"column:title"`
+}
+
+func (*document) TableName() string {
+	return "t_documents"
+}
+
+// memo embeds a flat primary key mixin , its BeforeCreate shadows any BeforeCreate of AuditedBy
+type memo struct {
+	UuidPrimaryKey
+	CreatedAt
+	DeletedAt
+	AuditedBy
+	Title string `gorm:"column:title"`
+}
+
+func (*memo) TableName() string {
+	return "t_memos"
+}
+
+func TestAuditedBy(t *testing.T) {
+	orm := newTestORM(t, new(document))
+	var err error
+	d := &document{Title: "d1"}
+	if err = Wrap(orm, Actor("u1")).Create(d).Error; err != nil {
+		t.Fatalf("create err %v", err)
+	}
+	if d.ID == "" || d.CreatedBy.CreatedBy != "u1" || d.UpdatedBy.UpdatedBy != "u1" {
+		t.Fatalf("unexpected created document %+v", d)
+	}
+	ctx := WithActor(context.Background(), "u2")
+	if err = orm.WithContext(ctx).Model(d).Update("title", "d2").Error; err != nil {
+		t.Fatalf("update err %v", err)
+	}
+	found := new(document)
+	orm.First(found, "id = ?", d.ID)
+	if found.CreatedBy.CreatedBy != "u1" || found.UpdatedBy.UpdatedBy != "u2" || found.Title != "d2" {
+		t.Fatalf("unexpected updated document %+v", found)
+	}
+	// an update restricted to selected columns records the actor too
+	d.Title = "d3"
+	if err = Wrap(orm, Actor("u6")).Model(d).Select("title").Updates(d).Error; err != nil {
+		t.Fatalf("select update err %v", err)
+	}
+	found = new(document)
+	orm.First(found, "id = ?", d.ID)
+	if found.UpdatedBy.UpdatedBy != "u6" || found.Title != "d3" || found.CreatedBy.CreatedBy != "u1" {
+		t.Fatalf("unexpected select updated document %+v", found)
+	}
+	other := &document{Title: "other"}
+	orm.Create(other)
+	if err = Wrap(orm, Actor("u3")).Delete(d).Error; err != nil {
+		t.Fatalf("delete err %v", err)
+	}
+	found = new(document)
+	orm.Unscoped().First(found, "id = ?", d.ID)
+	if found.DeletedBy.DeletedBy != "u3" || !found.DeletedAt.DeletedAt.Valid {
+		t.Fatalf("unexpected deleted document %+v", found)
+	}
+	orm.First(other, "id = ?", other.ID)
+	if other.DeletedBy.DeletedBy != "" {
+		t.Fatalf("other document marked deleted by '%s'", other.DeletedBy.DeletedBy)
+	}
+	// the creator is recorded with a flat primary key mixin too
+	orm = newTestORM(t, new(memo))
+	m := &memo{Title: "m1"}
+	if err = Wrap(orm, Actor("u4")).Create(m).Error; err != nil || m.ID == "" || m.CreatedBy.CreatedBy != "u4" {
+		t.Fatalf("unexpected created memo %+v , %v", m, err)
+	}
+	foundMemo := new(memo)
+	orm.First(foundMemo, "id = ?", m.ID)
+	if foundMemo.CreatedBy.CreatedBy != "u4" || foundMemo.UpdatedBy.UpdatedBy != "u4" {
+		t.Fatalf("unexpected found memo %+v", foundMemo)
+	}
+	// deleted_by is set by the soft delete update itself
+	stmt := Wrap(orm, Actor("u5")).Session(&gorm.Session{DryRun: true}).Delete(m).Statement
+	if sql := stmt.SQL.String(); !strings.HasPrefix(sql, "UPDATE") || !strings.Contains(sql, "deleted_by") {
+		t.Fatalf("unexpected soft delete sql %s", sql)
+	}
+	if err = Wrap(orm, Actor("u5")).Delete(m).Error; err != nil {
+		t.Fatalf("delete memo err %v", err)
+	}
+	foundMemo = new(memo)
+	orm.Unscoped().First(foundMemo, "id = ?", m.ID)
+	if foundMemo.DeletedBy.DeletedBy != "u5" || !foundMemo.DeletedAt.DeletedAt.Valid {
+		t.Fatalf("unexpected deleted memo %+v", foundMemo)
+	}
+	SetActorRequired(true)
+	defer SetActorRequired(false)
+	if err = orm.Create(&document{Title: "d3"}).Error; !errors.Is(err, ErrMissingActor) {
+		t.Fatalf("expect missing actor error , got %v", err)
+	}
+}
@@ -10,8 +10,10 @@ import (
 )
 
 type CreatedAt struct {
-	CreatedAt   time.Time `gorm:"column:created_at" json:"-"`
-	CreatedAtTs int64     `gorm:"-" json:"createdAt"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"-"`
+	// Deprecated: filled by AfterSave/AfterFind only , zero for values built in memory or read by Scan.
+	// use CreatedAtOf , which marshals the column itself in the timeFormat config
+	CreatedAtTs int64 `gorm:"-" json:"createdAt"`
 }
 
 func (this *CreatedAt) AfterSave(*gorm.DB) (err error) {
@@ -25,8 +27,10 @@ func (this *CreatedAt) AfterFind(*gorm.DB) (err error) {
 }
 
 type UpdatedAt struct {
-	UpdatedAt   time.Time `gorm:"column:updated_at" json:"-"`
-	UpdatedAtTs int64     `gorm:"-" json:"updatedAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"-"`
+	// Deprecated: filled by AfterSave/AfterFind only , zero for values built in memory or read by Scan.
+	// use UpdatedAtOf , which marshals the column itself in the timeFormat config
+	UpdatedAtTs int64 `gorm:"-" json:"updatedAt"`
 }
 
 func (this *UpdatedAt) AfterSave(*gorm.DB) (err error) {
@@ -40,8 +44,10 @@ func (this *UpdatedAt) AfterFind(*gorm.DB) (err error) {
 }
 
 type DeletedAt struct {
-	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
-	DeletedAtTs *int64         `gorm:"-" json:"deletedAt,omitempty"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
+	// Deprecated: filled by AfterSave/AfterFind only , nil for values built in memory or read by Scan.
+	// use DeletedAtOf , which marshals the column itself in the timeFormat config
+	DeletedAtTs *int64 `gorm:"-" json:"deletedAt,omitempty"`
 }
 
 func (this *DeletedAt) AfterSave(*gorm.DB) (err error) {
@@ -60,6 +66,21 @@ func (this *DeletedAt) AfterFind(*gorm.DB) (err error) {
 	return
 }
 
+// CreatedAtOf 创建时间，列本身按F格式输出json，不依赖钩子；F为 DefaultTimeFormat 时使用包级别格式(SetTimeFormat)
+type CreatedAtOf[F TimeFormat] struct {
+	CreatedAt TimestampOf[F] `gorm:"column:created_at" json:"createdAt"`
+}
+
+// UpdatedAtOf 更新时间，列本身按F格式输出json，不依赖钩子
+type UpdatedAtOf[F TimeFormat] struct {
+	UpdatedAt TimestampOf[F] `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// DeletedAtOf 软删除时间，列本身按F格式输出json，未删除时不输出
+type DeletedAtOf[F TimeFormat] struct {
+	DeletedAt DeletedTimestampOf[F] `gorm:"column:deleted_at" json:"deletedAt,omitzero"`
+}
+
 // UuidPrimaryKey UUID主键
 type UuidPrimaryKey struct {
 	ID string `gorm:"column:id;primaryKey;type:varchar(32)" json:"id"`
@@ -97,13 +118,11 @@ type UuidPriWithCreateAtBase struct {
 }
 
 func (this *UuidPriWithCreateAtBase) AfterSave(session *gorm.DB) (err error) {
-	_ = this.CreatedAt.AfterSave(session)
-	return
+	return RunMixinHooks(session, this, HookAfterSave)
 }
 
 func (this *UuidPriWithCreateAtBase) AfterFind(session *gorm.DB) (err error) {
-	_ = this.CreatedAt.AfterFind(session)
-	return
+	return RunMixinHooks(session, this, HookAfterFind)
 }
 
 // UuidPriWithCreateDelAtBase UUID主键 + CreatedAt + DeletedAt
@@ -114,15 +133,11 @@ type UuidPriWithCreateDelAtBase struct {
 }
 
 func (this *UuidPriWithCreateDelAtBase) AfterSave(session *gorm.DB) (err error) {
-	_ = this.CreatedAt.AfterSave(session)
-	_ = this.DeletedAt.AfterSave(session)
-	return
+	return RunMixinHooks(session, this, HookAfterSave)
 }
 
 func (this *UuidPriWithCreateDelAtBase) AfterFind(session *gorm.DB) (err error) {
-	_ = this.CreatedAt.AfterFind(session)
-	_ = this.DeletedAt.AfterFind(session)
-	return
+	return RunMixinHooks(session, this, HookAfterFind)
 }
 
 // Int64PriWithCreateAtBase 自增主键 + CreatedAt
@@ -132,15 +147,11 @@ type Int64PriWithCreateAtBase struct {
 }
 
 func (this *Int64PriWithCreateAtBase) AfterSave(session *gorm.DB) (err error) {
-	_ = this.Int64PrimaryKey.AfterSave(session)
-	_ = this.CreatedAt.AfterSave(session)
-	return
+	return RunMixinHooks(session, this, HookAfterSave)
 }
 
 func (this *Int64PriWithCreateAtBase) AfterFind(session *gorm.DB) (err error) {
-	_ = this.Int64PrimaryKey.AfterFind(session)
-	_ = this.CreatedAt.AfterFind(session)
-	return
+	return RunMixinHooks(session, this, HookAfterFind)
 }
 
 // Int64PriWithCreateDelAtBase 自增主键 + CreatedAt + DeletedAt
@@ -151,15 +162,281 @@ type Int64PriWithCreateDelAtBase struct {
 }
 
 func (this *Int64PriWithCreateDelAtBase) AfterSave(session *gorm.DB) (err error) {
-	_ = this.Int64PrimaryKey.AfterSave(session)
-	_ = this.CreatedAt.AfterSave(session)
-	_ = this.DeletedAt.AfterSave(session)
-	return
+	return RunMixinHooks(session, this, HookAfterSave)
 }
 
 func (this *Int64PriWithCreateDelAtBase) AfterFind(session *gorm.DB) (err error) {
-	_ = this.Int64PrimaryKey.AfterFind(session)
-	_ = this.CreatedAt.AfterFind(session)
-	_ = this.DeletedAt.AfterFind(session)
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// SnowflakePrimaryKey 雪花算法主键，由 BeforeCreate 按配置的 snowflakeNode 生成，不依赖数据库自增
+type SnowflakePrimaryKey struct {
+	ID int64 `gorm:"column:id;primaryKey;autoIncrement:false" json:"-"`
+	//当ID数值过大时，前端js处理会丢失精度导致ID不准确，故转换为string返回
+	IdStr string `gorm:"-" json:"id"`
+}
+
+func (this *SnowflakePrimaryKey) BeforeCreate(*gorm.DB) (err error) {
+	if this.ID == 0 {
+		this.ID = NextSnowflakeID()
+	}
+	return
+}
+
+func (this *SnowflakePrimaryKey) AfterSave(*gorm.DB) (err error) {
+	this.IdStr = fmt.Sprintf("%d", this.ID)
+	return
+}
+
+func (this *SnowflakePrimaryKey) AfterFind(*gorm.DB) (err error) {
+	this.IdStr = fmt.Sprintf("%d", this.ID)
+	return
+}
+
+// SnowflakePriWithCreateAtBase 雪花算法主键 + CreatedAt
+type SnowflakePriWithCreateAtBase struct {
+	SnowflakePrimaryKey
+	CreatedAt
+}
+
+func (this *SnowflakePriWithCreateAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *SnowflakePriWithCreateAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// SnowflakePriWithCreateDelAtBase 雪花算法主键 + CreatedAt + DeletedAt
+type SnowflakePriWithCreateDelAtBase struct {
+	SnowflakePrimaryKey
+	CreatedAt
+	DeletedAt
+}
+
+func (this *SnowflakePriWithCreateDelAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *SnowflakePriWithCreateDelAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UuidV7PrimaryKey UUIDv7主键，按时间有序，避免随机UUID导致的B树索引碎片，格式同 UuidPrimaryKey
+type UuidV7PrimaryKey struct {
+	ID string `gorm:"column:id;primaryKey;type:varchar(32)" json:"id"`
+}
+
+func (this *UuidV7PrimaryKey) BeforeCreate(*gorm.DB) (err error) {
+	if len(this.ID) == 0 {
+		this.ID, err = NewUuidV7()
+	}
+	return
+}
+
+// UuidV7NativePrimaryKey UUIDv7主键，使用postgres原生uuid类型，ID为标准带横线格式
+type UuidV7NativePrimaryKey struct {
+	ID string `gorm:"column:id;primaryKey;type:uuid" json:"id"`
+}
+
+func (this *UuidV7NativePrimaryKey) BeforeCreate(*gorm.DB) (err error) {
+	if len(this.ID) == 0 {
+		this.ID, err = NewUuidV7Native()
+	}
+	return
+}
+
+// UlidPrimaryKey ULID主键，26位按时间有序的字符串
+type UlidPrimaryKey struct {
+	ID string `gorm:"column:id;primaryKey;type:char(26)" json:"id"`
+}
+
+func (this *UlidPrimaryKey) BeforeCreate(*gorm.DB) (err error) {
+	if len(this.ID) == 0 {
+		this.ID, err = NewUlid()
+	}
 	return
 }
+
+// UuidV7PriWithCreateAtBase UUIDv7主键 + CreatedAt
+type UuidV7PriWithCreateAtBase struct {
+	UuidV7PrimaryKey
+	CreatedAt
+}
+
+func (this *UuidV7PriWithCreateAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UuidV7PriWithCreateAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UuidV7PriWithCreateDelAtBase UUIDv7主键 + CreatedAt + DeletedAt
+type UuidV7PriWithCreateDelAtBase struct {
+	UuidV7PrimaryKey
+	CreatedAt
+	DeletedAt
+}
+
+func (this *UuidV7PriWithCreateDelAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UuidV7PriWithCreateDelAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UuidV7NativePriWithCreateAtBase UUIDv7原生uuid主键 + CreatedAt
+type UuidV7NativePriWithCreateAtBase struct {
+	UuidV7NativePrimaryKey
+	CreatedAt
+}
+
+func (this *UuidV7NativePriWithCreateAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UuidV7NativePriWithCreateAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UuidV7NativePriWithCreateDelAtBase UUIDv7原生uuid主键 + CreatedAt + DeletedAt
+type UuidV7NativePriWithCreateDelAtBase struct {
+	UuidV7NativePrimaryKey
+	CreatedAt
+	DeletedAt
+}
+
+func (this *UuidV7NativePriWithCreateDelAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UuidV7NativePriWithCreateDelAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UlidPriWithCreateAtBase ULID主键 + CreatedAt
+type UlidPriWithCreateAtBase struct {
+	UlidPrimaryKey
+	CreatedAt
+}
+
+func (this *UlidPriWithCreateAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UlidPriWithCreateAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UlidPriWithCreateDelAtBase ULID主键 + CreatedAt + DeletedAt
+type UlidPriWithCreateDelAtBase struct {
+	UlidPrimaryKey
+	CreatedAt
+	DeletedAt
+}
+
+func (this *UlidPriWithCreateDelAtBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UlidPriWithCreateDelAtBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UuidPriWithFullTsBase UUID主键 + CreatedAt + UpdatedAt + DeletedAt
+type UuidPriWithFullTsBase struct {
+	UuidPrimaryKey
+	CreatedAt
+	UpdatedAt
+	DeletedAt
+}
+
+func (this *UuidPriWithFullTsBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UuidPriWithFullTsBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// Int64PriWithFullTsBase 自增主键 + CreatedAt + UpdatedAt + DeletedAt
+type Int64PriWithFullTsBase struct {
+	Int64PrimaryKey
+	CreatedAt
+	UpdatedAt
+	DeletedAt
+}
+
+func (this *Int64PriWithFullTsBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *Int64PriWithFullTsBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// SnowflakePriWithFullTsBase 雪花算法主键 + CreatedAt + UpdatedAt + DeletedAt
+type SnowflakePriWithFullTsBase struct {
+	SnowflakePrimaryKey
+	CreatedAt
+	UpdatedAt
+	DeletedAt
+}
+
+func (this *SnowflakePriWithFullTsBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *SnowflakePriWithFullTsBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UuidV7PriWithFullTsBase UUIDv7主键 + CreatedAt + UpdatedAt + DeletedAt
+type UuidV7PriWithFullTsBase struct {
+	UuidV7PrimaryKey
+	CreatedAt
+	UpdatedAt
+	DeletedAt
+}
+
+func (this *UuidV7PriWithFullTsBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UuidV7PriWithFullTsBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UuidV7NativePriWithFullTsBase UUIDv7原生uuid主键 + CreatedAt + UpdatedAt + DeletedAt
+type UuidV7NativePriWithFullTsBase struct {
+	UuidV7NativePrimaryKey
+	CreatedAt
+	UpdatedAt
+	DeletedAt
+}
+
+func (this *UuidV7NativePriWithFullTsBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UuidV7NativePriWithFullTsBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
+
+// UlidPriWithFullTsBase ULID主键 + CreatedAt + UpdatedAt + DeletedAt
+type UlidPriWithFullTsBase struct {
+	UlidPrimaryKey
+	CreatedAt
+	UpdatedAt
+	DeletedAt
+}
+
+func (this *UlidPriWithFullTsBase) AfterSave(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterSave)
+}
+
+func (this *UlidPriWithFullTsBase) AfterFind(session *gorm.DB) (err error) {
+	return RunMixinHooks(session, this, HookAfterFind)
+}
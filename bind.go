@@ -0,0 +1,182 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FieldError an invalid field of a request
+type FieldError struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (this FieldError) String() string {
+	return fmt.Sprintf("%s '%s' %s", this.Field, this.Value, this.Reason)
+}
+
+// ValidationError the request has invalid fields , returned by ParsePageRequest and BindPageRequest
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (this *ValidationError) Error() string {
+	items := make([]string, 0, len(this.Fields))
+	for _, it := range this.Fields {
+		items = append(items, it.String())
+	}
+	return "invalid page request : " + strings.Join(items, " ; ")
+}
+
+func (this *ValidationError) add(field, value, reason string) {
+	this.Fields = append(this.Fields, FieldError{Field: field, Value: value, Reason: reason})
+}
+
+func (this *ValidationError) orNil() error {
+	if len(this.Fields) == 0 {
+		return nil
+	}
+	return this
+}
+
+// ParsePageRequest parse page , pageSize , begin , end , key , orderBy , reserve , fields and
+// filter[field][op] from query values. every invalid field is reported by a *ValidationError
+func ParsePageRequest(values url.Values) (PageRequest, error) {
+	return parsePageRequest(func(key string) (string, bool) {
+		if !values.Has(key) {
+			return "", false
+		}
+		return values.Get(key), true
+	}, ParseFilter(values))
+}
+
+// BindPageRequest bind a PageRequest from r : a json body for POST , PUT and PATCH ,
+// otherwise the query , form and path values (see http.Request.PathValue)
+func BindPageRequest(r *http.Request) (PageRequest, error) {
+	if isJsonBody(r) {
+		return bindJsonPageRequest(r.Body)
+	}
+	if err := r.ParseForm(); err != nil {
+		return PageRequest{}, err
+	}
+	return parsePageRequest(func(key string) (string, bool) {
+		if v := r.PathValue(key); v != "" {
+			return v, true
+		}
+		if !r.Form.Has(key) {
+			return "", false
+		}
+		return r.Form.Get(key), true
+	}, ParseFilter(r.Form))
+}
+
+func isJsonBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func bindJsonPageRequest(body io.Reader) (PageRequest, error) {
+	ret := PageRequest{}
+	verr := &ValidationError{}
+	if err := json.NewDecoder(body).Decode(&ret); err != nil && err != io.EOF {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			verr.add(typeErr.Field, typeErr.Value, "must be "+typeErr.Type.String())
+			return PageRequest{}, verr
+		}
+		return PageRequest{}, err
+	}
+	validatePageRequest(ret, verr)
+	if err := verr.orNil(); err != nil {
+		return PageRequest{}, err
+	}
+	return ret, nil
+}
+
+func parsePageRequest(get func(key string) (string, bool), filter Filter) (PageRequest, error) {
+	ret := PageRequest{}
+	verr := &ValidationError{}
+	parseInt := func(key string) *int64 {
+		v, ok := get(key)
+		if !ok || v == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			verr.add(key, v, "must be an integer")
+			return nil
+		}
+		return &n
+	}
+	if n := parseInt("page"); n != nil {
+		page := int(*n)
+		ret.Page = &page
+	}
+	if n := parseInt("pageSize"); n != nil {
+		pageSize := int(*n)
+		ret.PageSize = &pageSize
+	}
+	if n := parseInt("begin"); n != nil {
+		ret.Begin = *n
+	}
+	ret.End = parseInt("end")
+	ret.Key, _ = get("key")
+	ret.OrderBy, _ = get("orderBy")
+	ret.Fields, _ = get("fields")
+	if v, ok := get("reserve"); ok && v != "" {
+		reserve, err := strconv.ParseBool(v)
+		if err != nil {
+			verr.add("reserve", v, "must be true or false")
+		}
+		ret.Reserve = reserve
+	}
+	if len(filter) > 0 {
+		ret.Filter = filter
+	}
+	validatePageRequest(ret, verr)
+	if err := verr.orNil(); err != nil {
+		return PageRequest{}, err
+	}
+	return ret, nil
+}
+
+// validatePageRequest the same rules as the validate tags of PageRequest , plus the MaxPageSize of
+// the package level PageLimits , DefaultMaxPageSize when unlimited (a clamped size is left to the query)
+func validatePageRequest(page PageRequest, verr *ValidationError) {
+	if page.Page != nil && *page.Page <= 0 {
+		verr.add("page", strconv.Itoa(*page.Page), "must be greater than 0")
+	}
+	if page.PageSize != nil {
+		if *page.PageSize <= 0 {
+			verr.add("pageSize", strconv.Itoa(*page.PageSize), "must be greater than 0")
+		} else if limits := GetPageLimits(); !limits.Clamp {
+			max := limits.MaxPageSize
+			if max <= 0 {
+				max = DefaultMaxPageSize
+			}
+			if *page.PageSize > max {
+				verr.add("pageSize", strconv.Itoa(*page.PageSize), fmt.Sprintf("must not be greater than %d", max))
+			}
+		}
+	}
+	if page.Begin < 0 {
+		verr.add("begin", strconv.FormatInt(page.Begin, 10), "must not be negative")
+	}
+	if page.End != nil && *page.End < page.Begin {
+		verr.add("end", strconv.FormatInt(*page.End, 10), "must not be less than begin")
+	}
+}
@@ -1,9 +1,13 @@
 package db
 
+import "time"
+
 const (
 	ModuleName      = "db"
 	CtxTraceIdKey   = "kboot-db-trace-id"
 	CtxTraceSkipKey = "kboot-db-trace-skip"
+	CtxLogFieldsKey = "kboot-db-log-fields"
+	CtxActorKey     = "kboot-db-actor"
 
 	cfgKeyDefault           = "default"
 	cfgKeyDbType            = "type"
@@ -11,9 +15,23 @@ const (
 	cfgKeyDbDebug           = "debug"
 	cfgKeyDbTimezone        = "timezone"
 	cfgKeyDbSlowThresholdMs = "slowThresholdMs"
+	cfgKeyDbLogLevel        = "logLevel"
+	cfgKeyDbSampleRate      = "sampleRate"
+	cfgKeyDbRateLimit       = "rateLimit"
+	cfgKeyDbSampleSummary   = "sampleSummarySec"
+	cfgKeyDbRecentQueries   = "recentQueries"
+	cfgKeyDbCursorSecret    = "cursorSecret"
+	cfgKeyDbSnowflakeNode   = "snowflakeNode"
+	cfgKeyDbTimeFormat      = "timeFormat"
+	cfgKeyDbRequireActor    = "requireActor"
 
 	DsTypePg      = "postgres"
 	DsTypeSqlLite = "sqlite"
+
+	LogLevelSilent = "silent"
+	LogLevelError  = "error"
+	LogLevelWarn   = "warn"
+	LogLevelInfo   = "info"
 )
 
 type Config struct {
@@ -23,4 +41,37 @@ type Config struct {
 	Debug           bool   `toml:"debug" mapstructure:"debug"`
 	SlowThresholdMs int64  `toml:"slowThresholdMs" validate:"gte=0" mapstructure:"slowThresholdMs"`
 	Colorful        *bool  `toml:"colorful" mapstructure:"colorful"`
+	// Timezone IANA name (e.g. Asia/Shanghai) of the time generated and rendered by the datasource ,
+	// the timezone of the application when empty
+	Timezone string `toml:"timezone" mapstructure:"timezone"`
+	// LogLevel one of silent,error,warn,info ; empty means warn , or info when Debug is on
+	LogLevel string `toml:"logLevel" validate:"omitempty,oneof=silent error warn info" mapstructure:"logLevel"`
+	// SampleRate log 1 in SampleRate normal statements , 0 or 1 means log all.
+	// error and slow statements are always logged
+	SampleRate int64 `toml:"sampleRate" validate:"gte=0" mapstructure:"sampleRate"`
+	// RateLimit max normal statements logged per statement fingerprint (the sql without its literal values)
+	// per second , 0 means unlimited
+	RateLimit int64 `toml:"rateLimit" validate:"gte=0" mapstructure:"rateLimit"`
+	// SampleSummarySec interval of the dropped entries summary line , default 60
+	SampleSummarySec int64 `toml:"sampleSummarySec" validate:"gte=0" mapstructure:"sampleSummarySec"`
+	// RecentQueries keep the latest RecentQueries statements in memory regardless of log level , 0 means disabled
+	RecentQueries int `toml:"recentQueries" validate:"gte=0" mapstructure:"recentQueries"`
+	// CursorSecret key to sign CursorQuery cursors , only read from the default datasource
+	CursorSecret string `toml:"cursorSecret" mapstructure:"cursorSecret"`
+	// SnowflakeNode node id (0-1023) of SnowflakePrimaryKey , must be unique per instance , derived from
+	// the hostname when not set. only read from the default datasource
+	SnowflakeNode *int64 `toml:"snowflakeNode" validate:"omitempty,gte=0,lte=1023" mapstructure:"snowflakeNode"`
+	// TimeFormat json format of the timestamp mixins , one of seconds , millis or rfc3339 , default seconds.
+	// only read from the default datasource
+	TimeFormat string `toml:"timeFormat" validate:"omitempty,oneof=seconds millis rfc3339" mapstructure:"timeFormat"`
+	// RequireActor the audit mixins fail when the context has no actor , only read from the default datasource
+	RequireActor bool `toml:"requireActor" mapstructure:"requireActor"`
+}
+
+// location the timezone of the datasource , def when not configured
+func (c *Config) location(def *time.Location) (*time.Location, error) {
+	if c.Timezone == "" {
+		return def, nil
+	}
+	return time.LoadLocation(c.Timezone)
 }
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ConnectionArgs relay connection arguments , first/after page forward , last/before page backward
+type ConnectionArgs struct {
+	First  *int    `json:"first" query:"first" form:"first" validate:"omitempty,gte=0"`
+	After  *string `json:"after" query:"after" form:"after"`
+	Last   *int    `json:"last" query:"last" form:"last" validate:"omitempty,gte=0"`
+	Before *string `json:"before" query:"before" form:"before"`
+
+	Key string `json:"key" query:"key" form:"key"`
+
+	OrderBy string `json:"orderBy" query:"orderBy" form:"orderBy"`
+
+	Reserve bool `json:"reserve" query:"reserve" form:"reserve"`
+
+	Filter Filter `json:"filter" query:"filter" form:"filter"`
+}
+
+func (this ConnectionArgs) cursorRequest() CursorRequest {
+	return CursorRequest{
+		Key:     this.Key,
+		OrderBy: this.OrderBy,
+		Reserve: this.Reserve,
+		Filter:  this.Filter,
+	}
+}
+
+type Edge[T any] struct {
+	Node   T      `json:"node"`
+	Cursor string `json:"cursor"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// Connection relay connection , TotalCount is 0 with WithCountMode(CountNone)
+type Connection[T any] struct {
+	Edges      []Edge[T] `json:"edges"`
+	PageInfo   PageInfo  `json:"pageInfo"`
+	TotalCount int64     `json:"totalCount"`
+}
+
+// ConnectionQuery relay cursor connection on top of CursorQuery , sharing its cursors , the
+// WithOrderCol whitelist , key and filter options. first and last can not be used together ,
+// neither given means first 10. TotalCount counts all rows matched regardless of after/before
+func ConnectionQuery[T schema.Tabler](tx *gorm.DB, args ConnectionArgs, m T,
+	opts ...PageOption) (*Connection[T], error) {
+	if args.First != nil && args.Last != nil {
+		return nil, errors.New("first and last can not be used together")
+	}
+	if (args.First != nil && *args.First < 0) || (args.Last != nil && *args.Last < 0) {
+		return nil, errors.New("first and last must not be negative")
+	}
+	ctx := newPageCtx(tx, opts...)
+	page := args.cursorRequest()
+	backward := args.Last != nil
+	limit, field := page.LimitV(), "first"
+	if backward {
+		limit, field = *args.Last, "last"
+	} else if args.First != nil {
+		limit = *args.First
+	}
+	limit, err := ctx.limitSize(field, limit)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ctx.prepareCursor(page, m)
+	if err != nil {
+		return nil, err
+	}
+	conn := &Connection[T]{Edges: make([]Edge[T], 0)}
+	if ctx.countMode != CountNone {
+		if conn.TotalCount, err = ctx.exactCount(m); err != nil {
+			return nil, err
+		}
+	}
+	hasAfter := args.After != nil && *args.After != ""
+	hasBefore := args.Before != nil && *args.Before != ""
+	if hasAfter {
+		if err = ctx.applyCursor(keys, page, *args.After, false); err != nil {
+			return nil, err
+		}
+	}
+	if hasBefore {
+		if err = ctx.applyCursor(keys, page, *args.Before, true); err != nil {
+			return nil, err
+		}
+	}
+	dbResults, hasMore, err := findCursorPage[T](ctx, keys, m, limit, backward)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dbResults {
+		cursor, err := keys.token(page, reflect.ValueOf(dbResults[i]))
+		if err != nil {
+			return nil, err
+		}
+		conn.Edges = append(conn.Edges, Edge[T]{Node: dbResults[i], Cursor: cursor})
+	}
+	// the opposite direction is only known to have rows when we came from a cursor
+	if backward {
+		conn.PageInfo.HasPreviousPage = hasMore
+		conn.PageInfo.HasNextPage = hasBefore
+	} else {
+		conn.PageInfo.HasNextPage = hasMore
+		conn.PageInfo.HasPreviousPage = hasAfter
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, nil
+}
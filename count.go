@@ -0,0 +1,269 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// count modes of PageQuery
+const (
+	// CountExact exact COUNT(*) , the default
+	CountExact = "exact"
+	// CountNone no count , PageResponse.HasMore tells whether there is a next page
+	CountNone = "none"
+	// CountEstimate planner estimate on postgres , exact elsewhere
+	CountEstimate = "estimate"
+	// CountCached exact count cached by the normalized query for a while
+	CountCached = "cached"
+
+	defaultCountCacheTTL = time.Minute
+	countCachePurgeSize  = 1024
+)
+
+// WithCountMode choose how PageQuery computes the total , one of CountExact , CountNone ,
+// CountEstimate or CountCached
+func WithCountMode(mode string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.countMode = mode
+	})
+}
+
+// WithCountCacheTTL how long a total is cached in CountCached mode , default 1 minute
+func WithCountCacheTTL(ttl time.Duration) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.countCacheTTL = ttl
+	})
+}
+
+// pageMeta how the total of a page was produced
+type pageMeta struct {
+	total     int64
+	countMode string
+	hasMore   *bool
+}
+
+type countCacheEntry struct {
+	total    int64
+	expireAt time.Time
+}
+
+// countCache totals of CountCached mode , keyed by the datasource , the count sql and its vars
+type countCache struct {
+	mu      sync.Mutex
+	entries map[string]countCacheEntry
+}
+
+var _countCache = &countCache{entries: make(map[string]countCacheEntry)}
+
+func (c *countCache) get(key string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(entry.expireAt) {
+		delete(c.entries, key)
+		return 0, false
+	}
+	return entry.total, true
+}
+
+func (c *countCache) set(key string, total int64, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= countCachePurgeSize {
+		for k, entry := range c.entries {
+			if now.After(entry.expireAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = countCacheEntry{total: total, expireAt: now.Add(ttl)}
+}
+
+// countQuery the count query of tx , rows are counted by COUNT(DISTINCT pk) when joins present
+func (ctx *pageCtx) countQuery(tx *gorm.DB, m interface{}) *gorm.DB {
+	tx = tx.Model(m).
+		Offset(-1).
+		Limit(-1)
+	if pk := ctx.joinedPkCol(); pk != "" {
+		tx = tx.Distinct(pk)
+	}
+	return tx
+}
+
+// countStatement the count sql of the current query , without executing it
+func (ctx *pageCtx) countStatement(m interface{}) *gorm.Statement {
+	var total int64
+	return ctx.countQuery(ctx.tx.Session(&gorm.Session{DryRun: true}), m).
+		Count(&total).Statement
+}
+
+// rowsStatement the sql of the rows counted by the current query (no aggregate , no limit) ,
+// without executing it
+func (ctx *pageCtx) rowsStatement(m interface{}) *gorm.Statement {
+	rows := make([]map[string]interface{}, 0)
+	return ctx.countQuery(ctx.tx.Session(&gorm.Session{DryRun: true}), m).
+		Find(&rows).Statement
+}
+
+// exactCount COUNT(*) without touching the statement of ctx.tx
+func (ctx *pageCtx) exactCount(m interface{}) (int64, error) {
+	var total int64
+	err := ctx.countQuery(ctx.tx.Session(&gorm.Session{}), m).
+		Count(&total).Error
+	return total, err
+}
+
+// cachedCount exact count cached by the datasource and the normalized count sql
+func (ctx *pageCtx) cachedCount(m interface{}) (int64, error) {
+	stmt := ctx.countStatement(m)
+	if stmt.Error != nil {
+		return 0, stmt.Error
+	}
+	// the same query on another datasource (e.g. a tenant shard) has its own total
+	sqlDB, err := ctx.tx.DB()
+	if err != nil {
+		return ctx.exactCount(m)
+	}
+	key := fmt.Sprintf("%p|%s|%v", sqlDB, stmt.SQL.String(), stmt.Vars)
+	if total, ok := _countCache.get(key); ok {
+		return total, nil
+	}
+	total, err := ctx.exactCount(m)
+	if err != nil {
+		return 0, err
+	}
+	ttl := ctx.countCacheTTL
+	if ttl <= 0 {
+		ttl = defaultCountCacheTTL
+	}
+	_countCache.set(key, total, ttl)
+	return total, nil
+}
+
+// estimateCount the planner estimate on postgres , pg_class.reltuples when the query counts the
+// whole table. ok is false when no estimate is available
+func (ctx *pageCtx) estimateCount(m interface{}) (total int64, ok bool, err error) {
+	if ctx.tx.Dialector.Name() != DsTypePg {
+		return 0, false, nil
+	}
+	s, err := modelSchema(ctx.tx, m)
+	if err != nil {
+		return 0, false, err
+	}
+	if ctx.unfiltered(s) {
+		var reltuples float64
+		err = ctx.tx.Session(&gorm.Session{NewDB: true}).
+			Raw("SELECT reltuples FROM pg_class WHERE oid = to_regclass(?)", s.Table).
+			Scan(&reltuples).Error
+		if err != nil {
+			return 0, false, err
+		}
+		// -1 means the table has never been analyzed
+		if reltuples >= 0 {
+			return int64(reltuples), true, nil
+		}
+	}
+	// the plan of the rows , a COUNT(*) plan may be parallel (Finalize Aggregate → Gather) and its
+	// inner nodes then estimate the partial aggregates , not the rows
+	stmt := ctx.rowsStatement(m)
+	if stmt.Error != nil {
+		return 0, false, stmt.Error
+	}
+	var plan string
+	err = ctx.tx.Session(&gorm.Session{NewDB: true}).
+		Raw("EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).
+		Row().Scan(&plan)
+	if err != nil {
+		return 0, false, err
+	}
+	var plans []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err = json.Unmarshal([]byte(plan), &plans); err != nil || len(plans) == 0 {
+		return 0, false, err
+	}
+	return int64(plans[0].Plan.PlanRows), true, nil
+}
+
+// unfiltered whether the query counts every row of table s , no condition , no join and
+// no soft delete condition (which gorm adds when the query is built)
+func (ctx *pageCtx) unfiltered(s *schema.Schema) bool {
+	stmt := ctx.tx.Statement
+	if _, filtered := stmt.Clauses["WHERE"]; filtered || len(stmt.Joins) > 0 {
+		return false
+	}
+	return stmt.Unscoped || softDeleteField(s) == nil
+}
+
+// findPage query the rows of page and compute the total by the count mode
+func findPage[T any](ctx *pageCtx, page PageRequest, m interface{}) ([]T, pageMeta, error) {
+	dbResults := make([]T, 0)
+	meta := pageMeta{countMode: CountExact}
+	switch ctx.countMode {
+	case CountNone:
+		meta.countMode = CountNone
+		q := ctx.tx.Model(m).
+			Offset(page.Offset()).
+			Limit(page.Limit() + 1)
+		err := ctx.dataQuery(q).Find(&dbResults).Error
+		if err != nil {
+			return nil, meta, err
+		}
+		hasMore := len(dbResults) > page.Limit()
+		if hasMore {
+			dbResults = dbResults[:page.Limit()]
+		}
+		meta.hasMore = &hasMore
+		return dbResults, meta, nil
+	case CountEstimate, CountCached:
+		var (
+			ok  bool
+			err error
+		)
+		if ctx.countMode == CountEstimate {
+			meta.total, ok, err = ctx.estimateCount(m)
+			if ok {
+				meta.countMode = CountEstimate
+			}
+		} else {
+			meta.total, err = ctx.cachedCount(m)
+			ok = err == nil
+			meta.countMode = CountCached
+		}
+		if err != nil {
+			return nil, meta, err
+		}
+		if !ok {
+			meta.countMode = CountExact
+			if meta.total, err = ctx.exactCount(m); err != nil {
+				return nil, meta, err
+			}
+		}
+		q := ctx.tx.Model(m).
+			Offset(page.Offset()).
+			Limit(page.Limit())
+		err = ctx.dataQuery(q).Find(&dbResults).Error
+		return dbResults, meta, err
+	default:
+		var err error
+		if meta.total, err = ctx.exactCount(m); err != nil {
+			return nil, meta, err
+		}
+		q := ctx.tx.Model(m).
+			Offset(page.Offset()).
+			Limit(page.Limit())
+		err = ctx.dataQuery(q).Find(&dbResults).Error
+		return dbResults, meta, err
+	}
+}
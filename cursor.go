@@ -0,0 +1,368 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var _cursorSecret atomic.Pointer[[]byte]
+
+func init() {
+	// random secret , cursors are only valid in this process until SetCursorSecret called
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	_cursorSecret.Store(&secret)
+}
+
+// SetCursorSecret set the key used to sign cursor tokens , all instances of a service
+// should share the same secret so that cursors stay valid across instances and restarts
+func SetCursorSecret(secret []byte) {
+	if len(secret) == 0 {
+		return
+	}
+	s := bytes.Clone(secret)
+	_cursorSecret.Store(&s)
+}
+
+type CursorRequest struct {
+	// Cursor opaque token returned by a previous CursorQuery , empty means the first page
+	Cursor string `json:"cursor" query:"cursor" form:"cursor"`
+	Limit  *int   `json:"limit" query:"limit" form:"limit" validate:"omitempty,gt=0"`
+	// Direction next or prev , relative to Cursor
+	Direction string `json:"direction" query:"direction" form:"direction" validate:"omitempty,oneof=next prev"`
+
+	Key string `json:"key" query:"key" form:"key"`
+
+	OrderBy string `json:"orderBy" query:"orderBy" form:"orderBy"`
+
+	Reserve bool `json:"reserve" query:"reserve" form:"reserve"`
+
+	Filter Filter `json:"filter" query:"filter" form:"filter"`
+}
+
+func (this CursorRequest) LimitV() int {
+	if this.Limit != nil && *this.Limit > 0 {
+		return *this.Limit
+	}
+	return 10
+}
+
+func (this CursorRequest) isPrev() bool {
+	return this.Cursor != "" && this.Direction == CursorPrev
+}
+
+type CursorResponse struct {
+	Next    string      `json:"next,omitempty"`
+	Prev    string      `json:"prev,omitempty"`
+	Limit   int         `json:"limit"`
+	Results interface{} `json:"results"`
+}
+
+// cursorToken the signed content of a cursor
+type cursorToken struct {
+	OrderBy string            `json:"o,omitempty"`
+	Reserve bool              `json:"r,omitempty"`
+	Values  []json.RawMessage `json:"v"`
+}
+
+func encodeCursor(token cursorToken) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, *_cursorSecret.Load())
+	mac.Write(payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+func decodeCursor(cursor string) (*cursorToken, error) {
+	enc := base64.RawURLEncoding
+	payloadStr, sigStr, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(payloadStr)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(sigStr)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, *_cursorSecret.Load())
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrInvalidCursor
+	}
+	token := new(cursorToken)
+	if err = json.Unmarshal(payload, token); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return token, nil
+}
+
+// cursorKeys the columns a cursor is made of , the sort columns then the primary key
+type cursorKeys struct {
+	cols   []string
+	fields []*schema.Field
+	desc   []bool
+}
+
+func (k *cursorKeys) token(page CursorRequest, row reflect.Value) (string, error) {
+	token := cursorToken{
+		OrderBy: page.OrderBy,
+		Reserve: page.Reserve,
+		Values:  make([]json.RawMessage, 0, len(k.fields)),
+	}
+	for _, field := range k.fields {
+		v, _ := field.ValueOf(context.Background(), row)
+		// the db value , not the json projection of the field (e.g. Timestamp in seconds)
+		if valuer, ok := v.(driver.Valuer); ok {
+			dv, err := valuer.Value()
+			if err != nil {
+				return "", err
+			}
+			v = dv
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		token.Values = append(token.Values, raw)
+	}
+	return encodeCursor(token)
+}
+
+// where build the keyset condition of rows after (or before when backward) the cursor values ,
+// NULL values are placed as the dialect sorts them : last in ASC on postgres , first in ASC elsewhere
+func (k *cursorKeys) where(token *cursorToken, backward bool, nullsLarge bool) (string, []interface{}, error) {
+	if len(token.Values) != len(k.fields) {
+		return "", nil, ErrInvalidCursor
+	}
+	values := make([]interface{}, 0, len(k.fields))
+	for i, field := range k.fields {
+		if bytes.Equal(token.Values[i], []byte("null")) {
+			values = append(values, nil)
+			continue
+		}
+		v := reflect.New(field.FieldType)
+		if err := json.Unmarshal(token.Values[i], v.Interface()); err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		values = append(values, v.Elem().Interface())
+	}
+	// (c1 > ?) OR (c1 = ? AND c2 > ?) , '<' for the DESC columns
+	ors := make([]string, 0, len(k.cols))
+	args := make([]interface{}, 0)
+	for i := range k.cols {
+		ands := make([]string, 0, i+1)
+		var andArgs []interface{}
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				ands = append(ands, fmt.Sprintf("%s IS NULL", k.cols[j]))
+				continue
+			}
+			ands = append(ands, fmt.Sprintf("%s = ?", k.cols[j]))
+			andArgs = append(andArgs, values[j])
+		}
+		op := ">"
+		if k.desc[i] != backward {
+			op = "<"
+		}
+		// whether the NULL rows come after the others in the scan order
+		nullsAfter := (op == ">") == nullsLarge
+		switch {
+		case values[i] == nil && nullsAfter:
+			// nothing follows NULL in this column
+			continue
+		case values[i] == nil:
+			ands = append(ands, fmt.Sprintf("%s IS NOT NULL", k.cols[i]))
+		case nullsAfter:
+			ands = append(ands, fmt.Sprintf("(%s %s ? OR %s IS NULL)", k.cols[i], op, k.cols[i]))
+			andArgs = append(andArgs, values[i])
+		default:
+			ands = append(ands, fmt.Sprintf("%s %s ?", k.cols[i], op))
+			andArgs = append(andArgs, values[i])
+		}
+		ors = append(ors, fmt.Sprintf("(%s)", strings.Join(ands, " AND ")))
+		args = append(args, andArgs...)
+	}
+	if len(ors) == 0 {
+		return "1 = 0", nil, nil
+	}
+	return fmt.Sprintf("(%s)", strings.Join(ors, " OR ")), args, nil
+}
+
+func (k *cursorKeys) order(backward bool) []orderItem {
+	orders := make([]orderItem, 0, len(k.cols))
+	for i, col := range k.cols {
+		orders = append(orders, orderItem{col: col, desc: k.desc[i] != backward})
+	}
+	return orders
+}
+
+func newCursorKeys(ctx *pageCtx, page CursorRequest, mSchema *schema.Schema) (*cursorKeys, error) {
+	if mSchema.PrioritizedPrimaryField == nil {
+		return nil, errors.Errorf("model '%s' has no primary key", mSchema.Name)
+	}
+	orders, err := parseOrderBy(page.OrderBy, page.Reserve, ctx.orderColsMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		// qualified like withPkTiebreaker , a joined table may have the same column
+		pk := fmt.Sprintf("%s.%s", mSchema.Table, mSchema.PrioritizedPrimaryField.DBName)
+		orders = append(orders, orderItem{col: pk, desc: page.Reserve})
+	}
+	orders = withPkTiebreaker(orders, mSchema)
+	keys := &cursorKeys{
+		cols:   make([]string, 0, len(orders)),
+		fields: make([]*schema.Field, 0, len(orders)),
+		desc:   make([]bool, 0, len(orders)),
+	}
+	for _, item := range orders {
+		col := item.col
+		if _, after, ok := strings.Cut(col, "."); ok {
+			col = after
+		}
+		field := mSchema.LookUpField(col)
+		if field == nil {
+			return nil, errors.Errorf("orderBy '%s' column '%s' not found in model '%s'", item.param,
+				item.col, mSchema.Name)
+		}
+		keys.cols = append(keys.cols, item.col)
+		keys.fields = append(keys.fields, field)
+		keys.desc = append(keys.desc, item.desc)
+	}
+	return keys, nil
+}
+
+// CursorQuery keyset pagination , rows are located by the signed cursor instead of OFFSET ,
+// and no total is counted , so it stays fast on huge tables.
+// the rows are ordered by page.OrderBy (checked by WithOrderCol whitelist) then the primary key.
+func CursorQuery[T schema.Tabler](tx *gorm.DB, page CursorRequest, m T, opts ...PageOption) (*CursorResponse, error) {
+	ctx := newPageCtx(tx, opts...)
+	keys, err := ctx.prepareCursor(page, m)
+	if err != nil {
+		return nil, err
+	}
+	backward := page.isPrev()
+	if page.Cursor != "" {
+		if err = ctx.applyCursor(keys, page, page.Cursor, backward); err != nil {
+			return nil, err
+		}
+	}
+	limit, err := ctx.limitSize("limit", page.LimitV())
+	if err != nil {
+		return nil, err
+	}
+	dbResults, hasMore, err := findCursorPage[T](ctx, keys, m, limit, backward)
+	if err != nil {
+		return nil, err
+	}
+	resp := &CursorResponse{
+		Limit:   limit,
+		Results: nil,
+	}
+	if len(dbResults) > 0 {
+		first := reflect.ValueOf(dbResults[0])
+		last := reflect.ValueOf(dbResults[len(dbResults)-1])
+		// forward : more rows after the page , and rows before it when we came from a cursor
+		// backward : more rows before the page , and always rows after it
+		if (!backward && hasMore) || backward {
+			if resp.Next, err = keys.token(page, last); err != nil {
+				return nil, err
+			}
+		}
+		if (backward && hasMore) || (!backward && page.Cursor != "") {
+			if resp.Prev, err = keys.token(page, first); err != nil {
+				return nil, err
+			}
+		}
+	}
+	resp.Results = convertResults(ctx, dbResults)
+	return resp, nil
+}
+
+// prepareCursor resolve the cursor keys of page and apply the joins , key and filter to ctx.tx
+func (ctx *pageCtx) prepareCursor(page CursorRequest, m interface{}) (*cursorKeys, error) {
+	mSchema, err := modelSchema(ctx.tx, m)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := newCursorKeys(ctx, page, mSchema)
+	if err != nil {
+		return nil, err
+	}
+	ctx.schema = mSchema
+	ctx.applyJoins()
+	if err = ctx.applyKey(page.Key, mSchema); err != nil {
+		return nil, err
+	}
+	if err = ctx.applyFilter(page.Filter, mSchema); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// applyCursor keep the rows after (or before when backward) cursor
+func (ctx *pageCtx) applyCursor(keys *cursorKeys, page CursorRequest, cursor string, backward bool) error {
+	token, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+	if token.OrderBy != page.OrderBy || token.Reserve != page.Reserve {
+		return errors.Wrap(ErrInvalidCursor, "cursor does not match the order")
+	}
+	query, args, err := keys.where(token, backward, ctx.tx.Dialector.Name() == DsTypePg)
+	if err != nil {
+		return err
+	}
+	ctx.tx = ctx.tx.Where(query, args...)
+	return nil
+}
+
+// findCursorPage query limit rows in the keys order (reversed when backward , the rows are then
+// flipped back) , hasMore tells whether more rows follow in the query direction
+func findCursorPage[T any](ctx *pageCtx, keys *cursorKeys, m interface{}, limit int, backward bool) ([]T, bool, error) {
+	dbResults := make([]T, 0)
+	ctx.applyOrders(keys.order(backward))
+	q := ctx.tx.Model(m).
+		Limit(limit + 1)
+	err := ctx.dataQuery(q).Find(&dbResults).Error
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(dbResults) > limit
+	if hasMore {
+		dbResults = dbResults[:limit]
+	}
+	if backward {
+		for i, j := 0, len(dbResults)-1; i < j; i, j = i+1, j-1 {
+			dbResults[i], dbResults[j] = dbResults[j], dbResults[i]
+		}
+	}
+	return dbResults, hasMore, nil
+}
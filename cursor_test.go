@@ -0,0 +1,170 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCursorQuery(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	for i := 0; i < 25; i++ {
+		orm.Create(&user{
+			Name: fmt.Sprintf("user%d", i),
+			Age:  i % 4,
+		})
+	}
+	limit := 10
+	page := CursorRequest{Limit: &limit, OrderBy: "age", Reserve: true}
+	seen := make(map[string]bool)
+	pages := make([]*CursorResponse, 0)
+	for {
+		res, err := CursorQuery[*user](orm, page, new(user), WithOrderCol("age"))
+		if err != nil {
+			t.Fatalf("query err %v", err)
+		}
+		pages = append(pages, res)
+		for _, u := range res.Results.([]*user) {
+			if seen[u.ID] {
+				t.Fatalf("user %s returned twice", u.ID)
+			}
+			seen[u.ID] = true
+		}
+		if res.Next == "" {
+			break
+		}
+		page.Cursor = res.Next
+	}
+	if len(seen) != 25 || len(pages) != 3 {
+		t.Fatalf("expect 25 users in 3 pages , got %d users in %d pages", len(seen), len(pages))
+	}
+	// go back from the last page
+	page.Cursor = pages[2].Prev
+	page.Direction = CursorPrev
+	res, err := CursorQuery[*user](orm, page, new(user), WithOrderCol("age"))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	got, expect := res.Results.([]*user), pages[1].Results.([]*user)
+	if len(got) != len(expect) {
+		t.Fatalf("expect %d users , got %d", len(expect), len(got))
+	}
+	for i := range got {
+		if got[i].ID != expect[i].ID {
+			t.Fatalf("prev page mismatch at %d", i)
+		}
+	}
+	// tampered cursor
+	page.Cursor = pages[1].Next + "x"
+	if _, err = CursorQuery[*user](orm, page, new(user), WithOrderCol("age")); err == nil {
+		t.Fatalf("expect invalid cursor error")
+	}
+}
+
+func TestConnectionQuery(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	for i := 0; i < 7; i++ {
+		orm.Create(&user{Name: fmt.Sprintf("user%d", i), Age: i})
+	}
+	first, last := 3, 2
+	args := ConnectionArgs{First: &first, OrderBy: "age"}
+	conn, err := ConnectionQuery[*user](orm, args, new(user), WithOrderCol("age"))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if conn.TotalCount != 7 || len(conn.Edges) != 3 || !conn.PageInfo.HasNextPage || conn.PageInfo.HasPreviousPage ||
+		conn.Edges[2].Node.Age != 2 || *conn.PageInfo.EndCursor != conn.Edges[2].Cursor {
+		t.Fatalf("unexpected first page %+v", conn.PageInfo)
+	}
+	args.After = conn.PageInfo.EndCursor
+	conn, err = ConnectionQuery[*user](orm, args, new(user), WithOrderCol("age"))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if len(conn.Edges) != 3 || conn.Edges[0].Node.Age != 3 || !conn.PageInfo.HasPreviousPage {
+		t.Fatalf("unexpected second page %+v", conn.PageInfo)
+	}
+	// the 2 rows before the second page
+	args = ConnectionArgs{Last: &last, Before: conn.PageInfo.StartCursor, OrderBy: "age"}
+	conn, err = ConnectionQuery[*user](orm, args, new(user), WithOrderCol("age"))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if len(conn.Edges) != 2 || conn.Edges[0].Node.Age != 1 || conn.Edges[1].Node.Age != 2 ||
+		!conn.PageInfo.HasPreviousPage || !conn.PageInfo.HasNextPage {
+		t.Fatalf("unexpected backward page %+v", conn.PageInfo)
+	}
+	// the last 2 rows
+	args = ConnectionArgs{Last: &last, OrderBy: "age"}
+	conn, _ = ConnectionQuery[*user](orm, args, new(user), WithOrderCol("age"), WithCountMode(CountNone))
+	if len(conn.Edges) != 2 || conn.Edges[1].Node.Age != 6 || conn.TotalCount != 0 {
+		t.Fatalf("unexpected last page %+v", conn)
+	}
+	args.First = &first
+	if _, err = ConnectionQuery[*user](orm, args, new(user), WithOrderCol("age")); err == nil {
+		t.Fatalf("expect first and last error")
+	}
+}
+
+type score struct {
+	Int64PrimaryKey
+	Points *int `gorm:"column:points"`
+}
+
+func (*score) TableName() string {
+	return "t_scores"
+}
+
+func TestCursorQueryNulls(t *testing.T) {
+	orm := newTestORM(t, new(score))
+	for i := 0; i < 10; i++ {
+		s := &score{}
+		if i%3 != 0 {
+			points := i % 4
+			s.Points = &points
+		}
+		orm.Create(s)
+	}
+	for _, reserve := range []bool{false, true} {
+		limit := 3
+		page := CursorRequest{Limit: &limit, OrderBy: "points", Reserve: reserve}
+		ids := make([]int64, 0)
+		var last *CursorResponse
+		for {
+			res, err := CursorQuery[*score](orm, page, new(score), WithOrderCol("points"))
+			if err != nil {
+				t.Fatalf("query err %v", err)
+			}
+			for _, s := range res.Results.([]*score) {
+				ids = append(ids, s.ID)
+			}
+			last = res
+			if res.Next == "" {
+				break
+			}
+			page.Cursor = res.Next
+		}
+		if len(ids) != 10 {
+			t.Fatalf("reserve %v : expect 10 scores , got %v", reserve, ids)
+		}
+		// walk back from the last page
+		page.Cursor, page.Direction = last.Prev, CursorPrev
+		back := len(last.Results.([]*score))
+		for page.Cursor != "" {
+			res, err := CursorQuery[*score](orm, page, new(score), WithOrderCol("points"))
+			if err != nil {
+				t.Fatalf("query err %v", err)
+			}
+			got := res.Results.([]*score)
+			for i, s := range got {
+				if expect := ids[len(ids)-back-len(got)+i]; s.ID != expect {
+					t.Fatalf("reserve %v : prev page mismatch , expect %d got %d", reserve, expect, s.ID)
+				}
+			}
+			back += len(got)
+			page.Cursor = res.Prev
+		}
+		if back != 10 {
+			t.Fatalf("reserve %v : expect 10 scores walking back , got %d", reserve, back)
+		}
+	}
+}
@@ -0,0 +1,437 @@
+package db
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// export formats
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportXLSX   = "xlsx"
+)
+
+// ExportConfig how ExportQuery writes the rows
+type ExportConfig struct {
+	// Format one of ExportCSV , ExportNDJSON , ExportXLSX
+	Format string
+	// Fields json names of the exported fields in order , empty means all fields
+	Fields []string
+	// Headers header of a field in csv and xlsx , default the json name
+	Headers map[string]string
+}
+
+// ExportQuery write all rows matched by page (filters , key , time window , order and fields , paging ignored)
+// to w , rows are read through a cursor so memory stays flat for huge exports , so WithPreload is ignored
+// (use WithJoin to read the columns of a relation).
+// fields and headers come from the json tags of the model (or of the WithResultConverter output).
+// string cells starting with = + - @ tab or CR are prefixed with ' in csv so spreadsheets do not run them
+// as formulas , xlsx writes them as string cells. it returns the rows written
+func ExportQuery[T schema.Tabler](tx *gorm.DB, page PageRequest, m T, w io.Writer, cfg ExportConfig,
+	opts ...PageOption) (int64, error) {
+	ctx := newPageCtx(tx, opts...)
+	if err := ctx.prepare(page, m); err != nil {
+		return 0, err
+	}
+	out, err := newExportWriter(w, cfg.Format)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := ctx.dataQuery(ctx.tx.Model(m)).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var (
+		cols  []string
+		count int64
+	)
+	modelType := reflect.TypeOf(m)
+	for rows.Next() {
+		var row interface{} = reflect.New(modelType.Elem()).Interface()
+		if err = ctx.tx.ScanRows(rows, row); err != nil {
+			return count, err
+		}
+		// ScanRows does not run the AfterFind hooks filling the json projections
+		if hook, ok := row.(interface{ AfterFind(*gorm.DB) error }); ok {
+			err = hook.AfterFind(ctx.tx)
+		} else {
+			err = RunMixinHooks(ctx.tx, row, HookAfterFind)
+		}
+		if err != nil {
+			return count, err
+		}
+		if ctx.resultConverter != nil {
+			row = ctx.resultConverter(row)
+		}
+		if cols == nil {
+			if cols, err = exportCols(row, cfg); err != nil {
+				return count, err
+			}
+			if err = out.header(cols, cfg.Headers); err != nil {
+				return count, err
+			}
+		}
+		if err = out.row(row, cols, len(cfg.Fields) > 0); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return count, err
+	}
+	if cols == nil {
+		// no rows , still write the header
+		if cols, err = exportCols(m, cfg); err != nil {
+			return 0, err
+		}
+		if err = out.header(cols, cfg.Headers); err != nil {
+			return 0, err
+		}
+	}
+	return count, out.close()
+}
+
+// exportCols the selected fields , checked against the json fields of row
+func exportCols(row interface{}, cfg ExportConfig) ([]string, error) {
+	all := jsonFieldNames(reflect.TypeOf(row))
+	if len(cfg.Fields) == 0 {
+		if len(all) == 0 && cfg.Format != ExportNDJSON {
+			return nil, errors.Errorf("export %s needs a struct , got %T", cfg.Format, row)
+		}
+		return all, nil
+	}
+	allowed := make(map[string]bool, len(all))
+	for _, it := range all {
+		allowed[it] = true
+	}
+	for _, field := range cfg.Fields {
+		if !allowed[field] {
+			return nil, errors.Errorf("export field '%s' not found , must be one of [%s]", field, strings.Join(all, ","))
+		}
+	}
+	return cfg.Fields, nil
+}
+
+// jsonFieldNames the json names of a struct in declaration order , embedded structs flattened
+func jsonFieldNames(t reflect.Type) []string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	ret := make([]string, 0, t.NumField())
+	seen := make(map[string]bool)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if !seen[name] {
+				seen[name] = true
+				ret = append(ret, name)
+			}
+		}
+	}
+	walk(t)
+	return ret
+}
+
+// jsonFields marshal row and split it by field
+func jsonFields(row interface{}) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// cellText the plain text of a json value , strings unquoted and null empty
+func cellText(raw json.RawMessage) (text string, isString bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", true
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s, true
+		}
+	}
+	return string(raw), raw[0] == '{' || raw[0] == '['
+}
+
+type exportWriter interface {
+	header(cols []string, headers map[string]string) error
+	row(row interface{}, cols []string, selected bool) error
+	close() error
+}
+
+func newExportWriter(w io.Writer, format string) (exportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case ExportNDJSON:
+		return &ndjsonExportWriter{w: bufio.NewWriter(w)}, nil
+	case ExportXLSX:
+		return newXlsxExportWriter(w)
+	default:
+		return nil, errors.Errorf("unknown export format '%s' , must be one of [%s,%s,%s]", format,
+			ExportCSV, ExportNDJSON, ExportXLSX)
+	}
+}
+
+func headerTexts(cols []string, headers map[string]string) []string {
+	ret := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if h, ok := headers[col]; ok {
+			ret = append(ret, h)
+		} else {
+			ret = append(ret, col)
+		}
+	}
+	return ret
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (this *csvExportWriter) header(cols []string, headers map[string]string) error {
+	return this.w.Write(headerTexts(cols, headers))
+}
+
+func (this *csvExportWriter) row(row interface{}, cols []string, _ bool) error {
+	fields, err := jsonFields(row)
+	if err != nil {
+		return err
+	}
+	record := make([]string, 0, len(cols))
+	for _, col := range cols {
+		text, isString := cellText(fields[col])
+		if isString {
+			text = csvSafe(text)
+		}
+		record = append(record, text)
+	}
+	return this.w.Write(record)
+}
+
+// csvSafe prefix the text a spreadsheet would evaluate as a formula with '
+func csvSafe(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func (this *csvExportWriter) close() error {
+	this.w.Flush()
+	return this.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	w *bufio.Writer
+}
+
+func (this *ndjsonExportWriter) header([]string, map[string]string) error {
+	return nil
+}
+
+func (this *ndjsonExportWriter) row(row interface{}, cols []string, selected bool) error {
+	if !selected {
+		raw, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		_, _ = this.w.Write(raw)
+		return this.w.WriteByte('\n')
+	}
+	fields, err := jsonFields(row)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBufferString("{")
+	for i, col := range cols {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(col)
+		buf.Write(key)
+		buf.WriteByte(':')
+		if v, ok := fields[col]; ok {
+			buf.Write(v)
+		} else {
+			buf.WriteString("null")
+		}
+	}
+	buf.WriteString("}\n")
+	_, err = this.w.Write(buf.Bytes())
+	return err
+}
+
+func (this *ndjsonExportWriter) close() error {
+	return this.w.Flush()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetBegin = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxExportWriter a minimal streaming xlsx writer , one sheet with inline strings
+type xlsxExportWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+}
+
+func newXlsxExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, it := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := zw.Create(it.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(f, it.content); err != nil {
+			return nil, err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	_, _ = sheet.WriteString(xlsxSheetBegin)
+	return &xlsxExportWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (this *xlsxExportWriter) writeRow(cells []string, strs []bool) error {
+	_, _ = this.sheet.WriteString("<row>")
+	for i, cell := range cells {
+		// inline strings are never evaluated as formulas , so the text is written as is
+		if strs[i] {
+			_, _ = this.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(this.sheet, []byte(xmlSafe(cell))); err != nil {
+				return err
+			}
+			_, _ = this.sheet.WriteString(`</t></is></c>`)
+		} else {
+			_, _ = this.sheet.WriteString("<c><v>")
+			if err := xml.EscapeText(this.sheet, []byte(cell)); err != nil {
+				return err
+			}
+			_, _ = this.sheet.WriteString("</v></c>")
+		}
+	}
+	_, err := this.sheet.WriteString("</row>")
+	return err
+}
+
+func (this *xlsxExportWriter) header(cols []string, headers map[string]string) error {
+	strs := make([]bool, len(cols))
+	for i := range strs {
+		strs[i] = true
+	}
+	return this.writeRow(headerTexts(cols, headers), strs)
+}
+
+func (this *xlsxExportWriter) row(row interface{}, cols []string, _ bool) error {
+	fields, err := jsonFields(row)
+	if err != nil {
+		return err
+	}
+	cells := make([]string, 0, len(cols))
+	strs := make([]bool, 0, len(cols))
+	for _, col := range cols {
+		raw := fields[col]
+		text, isString := cellText(raw)
+		// booleans are written as text , numbers as numeric cells
+		if !isString && (text == "true" || text == "false") {
+			isString = true
+		}
+		cells = append(cells, text)
+		strs = append(strs, isString)
+	}
+	return this.writeRow(cells, strs)
+}
+
+func (this *xlsxExportWriter) close() error {
+	if _, err := this.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := this.sheet.Flush(); err != nil {
+		return err
+	}
+	return this.zw.Close()
+}
+
+// xmlSafe drop the characters not allowed in xml 1.0
+func xmlSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) ||
+			(r >= 0x10000 && r <= 0x10FFFF) {
+			return r
+		}
+		return -1
+	}, s)
+}
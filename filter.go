@@ -0,0 +1,258 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// filter operators
+const (
+	FilterEq      = "eq"
+	FilterNe      = "ne"
+	FilterLt      = "lt"
+	FilterLte     = "lte"
+	FilterGt      = "gt"
+	FilterGte     = "gte"
+	FilterIn      = "in"
+	FilterNin     = "nin"
+	FilterLike    = "like"
+	FilterILike   = "ilike"
+	FilterIsNull  = "isnull"
+	FilterBetween = "between"
+)
+
+var allFilterOps = []string{
+	FilterEq, FilterNe, FilterLt, FilterLte, FilterGt, FilterGte,
+	FilterIn, FilterNin, FilterLike, FilterILike, FilterIsNull, FilterBetween,
+}
+
+var _timeType = reflect.TypeOf(time.Time{})
+
+// Filter field filters of a page request , field -> operator -> value ,
+// in , nin and between take comma separated values , isnull takes true or false
+type Filter map[string]map[string]string
+
+// FilterableCol declare a field allowed to be filtered
+type FilterableCol struct {
+	// Param public field name used in the request
+	Param string
+	// Col column name , empty means same as Param
+	Col string
+	// Ops allowed operators , empty means all
+	Ops []string
+}
+
+func (this FilterableCol) allow(op string) bool {
+	if len(this.Ops) == 0 {
+		return true
+	}
+	for _, it := range this.Ops {
+		if it == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (this FilterableCol) allowedOps() []string {
+	if len(this.Ops) == 0 {
+		return allFilterOps
+	}
+	return this.Ops
+}
+
+// WithFilterableCol allow filtering param (mapped to column col) with the given operators ,
+// no ops means all operators are allowed
+func WithFilterableCol(param string, col string, ops ...string) PageOption {
+	return WithFilterableCols(FilterableCol{Param: param, Col: col, Ops: ops})
+}
+
+// WithFilterableCols allow filtering the declared fields
+func WithFilterableCols(cols ...FilterableCol) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		for _, col := range cols {
+			if col.Col == "" {
+				col.Col = col.Param
+			}
+			ctx.filterCols[col.Param] = col
+		}
+	})
+}
+
+// ParseFilter collect filter[field][op]=value parameters from query values
+func ParseFilter(values url.Values) Filter {
+	ret := make(Filter)
+	for key, vs := range values {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") || len(vs) == 0 {
+			continue
+		}
+		// filter[field][op]
+		parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]"), "][")
+		field, op := parts[0], FilterEq
+		if len(parts) == 2 {
+			op = parts[1]
+		} else if len(parts) > 2 {
+			continue
+		}
+		if _, ok := ret[field]; !ok {
+			ret[field] = make(map[string]string)
+		}
+		ret[field][op] = vs[len(vs)-1]
+	}
+	return ret
+}
+
+// applyFilter validate filter against the filterable cols and add the conditions
+func (ctx *pageCtx) applyFilter(filter Filter, s *schema.Schema) error {
+	if len(filter) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(filter))
+	for field := range filter {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		col, ok := ctx.filterCols[field]
+		if !ok {
+			return errors.Errorf("filter '%s' not allowed , must be one of [%s]", field, mkFilterString(ctx.filterCols))
+		}
+		ops := make([]string, 0, len(filter[field]))
+		for op := range filter[field] {
+			ops = append(ops, op)
+		}
+		sort.Strings(ops)
+		for _, op := range ops {
+			if !col.allow(op) {
+				return errors.Errorf("filter '%s' operator '%s' not allowed , must be one of [%s]", field, op,
+					strings.Join(col.allowedOps(), ","))
+			}
+			query, args, err := buildFilterCond(ctx.tx, col, op, filter[field][op], s)
+			if err != nil {
+				return err
+			}
+			ctx.tx = ctx.tx.Where(query, args...)
+		}
+	}
+	return nil
+}
+
+func buildFilterCond(tx *gorm.DB, col FilterableCol, op string, value string, s *schema.Schema) (string, []interface{}, error) {
+	var field *schema.Field
+	if s != nil {
+		name := col.Col
+		if _, after, ok := strings.Cut(name, "."); ok {
+			name = after
+		}
+		field = s.LookUpField(name)
+	}
+	convert := func(v string) (interface{}, error) {
+		ret, err := convertFilterValue(field, strings.TrimSpace(v))
+		if err != nil {
+			return nil, errors.Errorf("filter '%s' operator '%s' invalid value '%s' : %v", col.Param, op, v, err)
+		}
+		return ret, nil
+	}
+	switch op {
+	case FilterEq, FilterNe, FilterLt, FilterLte, FilterGt, FilterGte:
+		v, err := convert(value)
+		if err != nil {
+			return "", nil, err
+		}
+		sqlOp := map[string]string{
+			FilterEq: "=", FilterNe: "<>", FilterLt: "<", FilterLte: "<=", FilterGt: ">", FilterGte: ">=",
+		}[op]
+		return fmt.Sprintf("%s %s ?", col.Col, sqlOp), []interface{}{v}, nil
+	case FilterIn, FilterNin:
+		args := make([]interface{}, 0)
+		for _, it := range strings.Split(value, ",") {
+			v, err := convert(it)
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, v)
+		}
+		if op == FilterNin {
+			return fmt.Sprintf("%s NOT IN ?", col.Col), []interface{}{args}, nil
+		}
+		return fmt.Sprintf("%s IN ?", col.Col), []interface{}{args}, nil
+	case FilterLike, FilterILike:
+		query, arg := likeCond(tx, col.Col, value, MatchContains, op == FilterILike)
+		return query, []interface{}{arg}, nil
+	case FilterIsNull:
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", nil, errors.Errorf("filter '%s' operator '%s' invalid value '%s' , must be true or false",
+				col.Param, op, value)
+		}
+		if isNull {
+			return fmt.Sprintf("%s IS NULL", col.Col), nil, nil
+		}
+		return fmt.Sprintf("%s IS NOT NULL", col.Col), nil, nil
+	case FilterBetween:
+		parts := strings.Split(value, ",")
+		if len(parts) != 2 {
+			return "", nil, errors.Errorf("filter '%s' operator '%s' invalid value '%s' , must be 'from,to'",
+				col.Param, op, value)
+		}
+		from, err := convert(parts[0])
+		if err != nil {
+			return "", nil, err
+		}
+		to, err := convert(parts[1])
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s BETWEEN ? AND ?", col.Col), []interface{}{from, to}, nil
+	default:
+		return "", nil, errors.Errorf("unknown filter operator '%s' , must be one of [%s]", op,
+			strings.Join(allFilterOps, ","))
+	}
+}
+
+// convertFilterValue convert the raw string to the go type of field , raw string is returned
+// when the field is unknown
+func convertFilterValue(field *schema.Field, v string) (interface{}, error) {
+	if field == nil {
+		return v, nil
+	}
+	t := field.IndirectFieldType
+	switch {
+	case t == _timeType || field.DataType == schema.Time:
+		// unix seconds like PageRequest.Begin/End , or RFC3339
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(ts, 0), nil
+		}
+		return time.Parse(time.RFC3339, v)
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(v, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(v, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(v, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(v)
+	default:
+		return v, nil
+	}
+}
+
+func mkFilterString(cols map[string]FilterableCol) string {
+	names := make([]string, 0, len(cols))
+	for k := range cols {
+		names = append(names, fmt.Sprintf("'%s'", k))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
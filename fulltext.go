@@ -0,0 +1,212 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var _identRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ErrFts5Unavailable the sqlite driver is built without the fts5 module
+var ErrFts5Unavailable = errors.New("sqlite fts5 module not available , build with -tags sqlite_fts5")
+
+// FullTextSearch full text search settings of PageRequest.Key
+type FullTextSearch struct {
+	// Cols columns searched
+	Cols []string
+	// Language postgres text search config , default simple
+	Language string
+	// VectorCol postgres generated tsvector column , used instead of Cols when set
+	VectorCol string
+	// FtsTable sqlite fts5 virtual table , default <table>_fts
+	FtsTable string
+	// RankParam orderBy param of the relevance , higher is better , default rank
+	RankParam string
+}
+
+func (this FullTextSearch) language() string {
+	if this.Language == "" {
+		return "simple"
+	}
+	return this.Language
+}
+
+func (this FullTextSearch) rankParam() string {
+	if this.RankParam == "" {
+		return "rank"
+	}
+	return this.RankParam
+}
+
+func (this FullTextSearch) ftsTable(s *schema.Schema) string {
+	if this.FtsTable == "" {
+		return s.Table + "_fts"
+	}
+	return this.FtsTable
+}
+
+func (this FullTextSearch) check() error {
+	if len(this.Cols) == 0 && this.VectorCol == "" {
+		return errors.New("full text search needs Cols or VectorCol")
+	}
+	for _, it := range append([]string{this.language(), this.VectorCol, this.FtsTable}, this.Cols...) {
+		if it != "" && !_identRegexp.MatchString(it) {
+			return errors.Errorf("invalid full text search identifier '%s'", it)
+		}
+	}
+	return nil
+}
+
+// pgDocument the tsvector expression searched on postgres
+func (this FullTextSearch) pgDocument() string {
+	if this.VectorCol != "" {
+		return this.VectorCol
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", this.language(), this.pgConcat())
+}
+
+func (this FullTextSearch) pgConcat() string {
+	cols := make([]string, 0, len(this.Cols))
+	for _, col := range this.Cols {
+		cols = append(cols, fmt.Sprintf("coalesce(%s,'')", col))
+	}
+	return strings.Join(cols, " || ' ' || ")
+}
+
+func (this FullTextSearch) pgQuery() string {
+	return fmt.Sprintf("websearch_to_tsquery('%s', ?)", this.language())
+}
+
+// WithFullTextSearch match PageRequest.Key by full text search instead of LIKE ,
+// using to_tsvector/websearch_to_tsquery on postgres and a fts5 virtual table on sqlite
+// (see CreateFullTextIndex). the relevance can be ordered by fts.RankParam , e.g. orderBy=-rank
+func WithFullTextSearch(fts FullTextSearch) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.fullText = &fts
+	})
+}
+
+// fts5Match quote every term of key as a fts5 string , so the user input is never parsed as fts5 syntax
+func fts5Match(key string) string {
+	terms := strings.Fields(key)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// fullTextCond the condition matching key
+func (ctx *pageCtx) fullTextCond(key string, s *schema.Schema) (string, []interface{}, error) {
+	fts := ctx.fullText
+	if err := fts.check(); err != nil {
+		return "", nil, err
+	}
+	switch ctx.tx.Dialector.Name() {
+	case DsTypePg:
+		return fmt.Sprintf("%s @@ %s", fts.pgDocument(), fts.pgQuery()), []interface{}{key}, nil
+	case DsTypeSqlLite:
+		table := fts.ftsTable(s)
+		return fmt.Sprintf("%s.rowid IN (SELECT rowid FROM %s WHERE %s MATCH ?)", s.Table, table, table),
+			[]interface{}{fts5Match(key)}, nil
+	default:
+		return "", nil, errors.Errorf("full text search not supported by '%s'", ctx.tx.Dialector.Name())
+	}
+}
+
+// fullTextRank the relevance expression of key , higher is better
+func (ctx *pageCtx) fullTextRank(key string, s *schema.Schema) (string, []interface{}) {
+	fts := ctx.fullText
+	if ctx.tx.Dialector.Name() == DsTypePg {
+		return fmt.Sprintf("ts_rank(%s, %s)", fts.pgDocument(), fts.pgQuery()), []interface{}{key}
+	}
+	// fts5 rank is lower for better matches
+	table := fts.ftsTable(s)
+	return fmt.Sprintf("(-(SELECT rank FROM %s WHERE %s MATCH ? AND rowid = %s.rowid))", table, table, s.Table),
+		[]interface{}{fts5Match(key)}
+}
+
+// CreateFullTextIndex create what WithFullTextSearch needs for model m :
+// on postgres the generated tsvector column (when VectorCol set) and a GIN index ,
+// on sqlite the fts5 virtual table with triggers keeping it in sync with the model table ,
+// ErrFts5Unavailable when the driver is built without fts5
+func CreateFullTextIndex(tx *gorm.DB, m interface{}, fts FullTextSearch) error {
+	if err := fts.check(); err != nil {
+		return err
+	}
+	s, err := modelSchema(tx, m)
+	if err != nil {
+		return err
+	}
+	switch tx.Dialector.Name() {
+	case DsTypePg:
+		return createPgFullTextIndex(tx, s, fts)
+	case DsTypeSqlLite:
+		return createSqliteFullTextIndex(tx, s, fts)
+	default:
+		return errors.Errorf("full text search not supported by '%s'", tx.Dialector.Name())
+	}
+}
+
+func createPgFullTextIndex(tx *gorm.DB, s *schema.Schema, fts FullTextSearch) error {
+	stmts := make([]string, 0, 2)
+	indexExpr := fts.pgDocument()
+	indexName := fmt.Sprintf("idx_%s_fts", s.Table)
+	if fts.VectorCol != "" {
+		if len(fts.Cols) == 0 {
+			return errors.New("full text search needs Cols to generate VectorCol")
+		}
+		stmts = append(stmts, fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s tsvector GENERATED ALWAYS AS (to_tsvector('%s', %s)) STORED",
+			s.Table, fts.VectorCol, fts.language(), fts.pgConcat()))
+		indexName = fmt.Sprintf("idx_%s_%s", s.Table, fts.VectorCol)
+	}
+	stmts = append(stmts, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s)", indexName, s.Table, indexExpr))
+	return execAll(tx, stmts)
+}
+
+func createSqliteFullTextIndex(tx *gorm.DB, s *schema.Schema, fts FullTextSearch) error {
+	if len(fts.Cols) == 0 {
+		return errors.New("sqlite full text search needs Cols")
+	}
+	var fts5 bool
+	if err := tx.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	if !fts5 {
+		return ErrFts5Unavailable
+	}
+	table := fts.ftsTable(s)
+	cols := strings.Join(fts.Cols, ", ")
+	newCols := "new." + strings.Join(fts.Cols, ", new.")
+	oldCols := "old." + strings.Join(fts.Cols, ", old.")
+	stmts := []string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content='%s', content_rowid='rowid')",
+			table, cols, s.Table),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ai AFTER INSERT ON %s BEGIN "+
+			"INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s); END",
+			table, s.Table, table, cols, newCols),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ad AFTER DELETE ON %s BEGIN "+
+			"INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s); END",
+			table, s.Table, table, table, cols, oldCols),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_au AFTER UPDATE ON %s BEGIN "+
+			"INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s); "+
+			"INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s); END",
+			table, s.Table, table, table, cols, oldCols, table, cols, newCols),
+		fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", table, table),
+	}
+	return execAll(tx, stmts)
+}
+
+func execAll(tx *gorm.DB, stmts []string) error {
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
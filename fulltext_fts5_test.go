@@ -0,0 +1,38 @@
+//go:build sqlite_fts5
+
+package db
+
+import (
+	"testing"
+)
+
+func TestFullTextSearch(t *testing.T) {
+	orm := newTestORM(t, new(post))
+	orm.Create(&post{Title: "go generics", Content: "type parameters in go"})
+	orm.Create(&post{Title: "rust", Content: "ownership"})
+	if err := CreateFullTextIndex(orm, new(post), postFts); err != nil {
+		t.Fatalf("create index err %v", err)
+	}
+	// rows inserted after the index are synced by the triggers
+	orm.Create(&post{Title: "go modules", Content: "versions"})
+	orm.Create(&post{Title: "weekly", Content: "go go go"})
+	res, err := PageQuery[*post](orm, PageRequest{Key: "go", OrderBy: "-rank"}, new(post),
+		WithFullTextSearch(postFts))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	results := res.Results.([]*post)
+	if res.Total != 3 || len(results) != 3 || results[0].Title != "weekly" {
+		t.Fatalf("unexpected results %+v", res)
+	}
+	// user input is not parsed as fts5 syntax
+	res, err = PageQuery[*post](orm, PageRequest{Key: `rust" OR "go`}, new(post), WithFullTextSearch(postFts))
+	if err != nil || res.Total != 0 {
+		t.Fatalf("expect no match , got %v %v", res, err)
+	}
+	orm.Model(&post{}).Where("title = ?", "rust").Update("content", "borrow checker")
+	res, _ = PageQuery[*post](orm, PageRequest{Key: "borrow"}, new(post), WithFullTextSearch(postFts))
+	if res.Total != 1 {
+		t.Fatalf("expect the updated row , got %d", res.Total)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !sqlite_fts5
+
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateFullTextIndexWithoutFts5(t *testing.T) {
+	orm := newTestORM(t, new(post))
+	if err := CreateFullTextIndex(orm, new(post), postFts); !errors.Is(err, ErrFts5Unavailable) {
+		t.Fatalf("expect fts5 unavailable error , got %v", err)
+	}
+}
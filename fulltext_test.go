@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+)
+
+type post struct {
+	Int64PrimaryKey
+	Title   string `gorm:"column:title"`
+	Content string `gorm:"column:content"`
+}
+
+func (*post) TableName() string {
+	return "t_posts"
+}
+
+var postFts = FullTextSearch{Cols: []string{"title", "content"}}
+
+func TestFts5Match(t *testing.T) {
+	if got := fts5Match(` go  "lang" OR x*`); got != `"go" """lang""" "OR" "x*"` {
+		t.Fatalf("unexpected match %s", got)
+	}
+}
+
+func TestFullTextSearchCheck(t *testing.T) {
+	for _, fts := range []FullTextSearch{
+		{},
+		{Cols: []string{"title; DROP TABLE t_posts"}},
+		{Cols: []string{"title"}, Language: "english'"},
+	} {
+		if err := fts.check(); err == nil {
+			t.Fatalf("expect invalid full text search %+v", fts)
+		}
+	}
+	if err := postFts.check(); err != nil {
+		t.Fatalf("check err %v", err)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/guestin/kboot-db-starter
 go 1.25
 
 require (
+	github.com/google/uuid v1.6.0
 	github.com/guestin/kboot v0.1.0-beta.8
 	github.com/guestin/log v1.0.3
 	github.com/guestin/mob v1.1.2
@@ -22,7 +23,6 @@ require (
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/go-playground/validator/v10 v10.28.0 // indirect
 	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/pgx/v5 v5.7.6 // indirect
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/schema"
+)
+
+// gorm hooks run on mixins
+const (
+	HookBeforeCreate = "BeforeCreate"
+	HookAfterCreate  = "AfterCreate"
+	HookBeforeUpdate = "BeforeUpdate"
+	HookAfterUpdate  = "AfterUpdate"
+	HookBeforeSave   = "BeforeSave"
+	HookAfterSave    = "AfterSave"
+	HookBeforeDelete = "BeforeDelete"
+	HookAfterDelete  = "AfterDelete"
+	HookAfterFind    = "AfterFind"
+)
+
+// RunMixinHooks call hook on every mixin embedded in model (a pointer to struct) , a mixin without
+// the hook is searched for nested mixins. all mixins are called and their errors are joined ,
+// so a composed model forwards a hook by one line :
+//
+//	func (this *User) AfterFind(tx *gorm.DB) error {
+//		return db.RunMixinHooks(tx, this, db.HookAfterFind)
+//	}
+func RunMixinHooks(tx *gorm.DB, model interface{}, hook string) error {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return runMixinHooks(tx, v.Elem(), hook)
+}
+
+func runMixinHooks(tx *gorm.DB, v reflect.Value, hook string) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.Anonymous || !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.Struct || !fv.CanAddr() {
+			continue
+		}
+		called, err := callHook(tx, fv.Addr().Interface(), hook)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !called {
+			if err = runMixinHooks(tx, fv, hook); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// callHook call hook of v , called is false when v has no such hook
+func callHook(tx *gorm.DB, v interface{}, hook string) (called bool, err error) {
+	switch hook {
+	case HookBeforeCreate:
+		if h, ok := v.(callbacks.BeforeCreateInterface); ok {
+			return true, h.BeforeCreate(tx)
+		}
+	case HookAfterCreate:
+		if h, ok := v.(callbacks.AfterCreateInterface); ok {
+			return true, h.AfterCreate(tx)
+		}
+	case HookBeforeUpdate:
+		if h, ok := v.(callbacks.BeforeUpdateInterface); ok {
+			return true, h.BeforeUpdate(tx)
+		}
+	case HookAfterUpdate:
+		if h, ok := v.(callbacks.AfterUpdateInterface); ok {
+			return true, h.AfterUpdate(tx)
+		}
+	case HookBeforeSave:
+		if h, ok := v.(callbacks.BeforeSaveInterface); ok {
+			return true, h.BeforeSave(tx)
+		}
+	case HookAfterSave:
+		if h, ok := v.(callbacks.AfterSaveInterface); ok {
+			return true, h.AfterSave(tx)
+		}
+	case HookBeforeDelete:
+		if h, ok := v.(callbacks.BeforeDeleteInterface); ok {
+			return true, h.BeforeDelete(tx)
+		}
+	case HookAfterDelete:
+		if h, ok := v.(callbacks.AfterDeleteInterface); ok {
+			return true, h.AfterDelete(tx)
+		}
+	case HookAfterFind:
+		if h, ok := v.(callbacks.AfterFindInterface); ok {
+			return true, h.AfterFind(tx)
+		}
+	}
+	return false, nil
+}
+
+// modelHasHook whether gorm calls hook on the model itself
+func modelHasHook(s *schema.Schema, hook string) bool {
+	switch hook {
+	case HookBeforeCreate:
+		return s.BeforeCreate
+	case HookAfterCreate:
+		return s.AfterCreate
+	case HookBeforeUpdate:
+		return s.BeforeUpdate
+	case HookAfterUpdate:
+		return s.AfterUpdate
+	case HookBeforeSave:
+		return s.BeforeSave
+	case HookAfterSave:
+		return s.AfterSave
+	case HookBeforeDelete:
+		return s.BeforeDelete
+	case HookAfterDelete:
+		return s.AfterDelete
+	case HookAfterFind:
+		return s.AfterFind
+	}
+	return false
+}
+
+// mixinHooksPlugin a hook defined by several embedded mixins is ambiguous , so the model has no such
+// hook and gorm calls none of them. the plugin runs the hooks of the mixins for such models ,
+// models defining the hook themselves are left to it (see RunMixinHooks)
+type mixinHooksPlugin struct{}
+
+func (mixinHooksPlugin) Name() string {
+	return "kboot:mixin_hooks"
+}
+
+func (mixinHooksPlugin) Initialize(db *gorm.DB) error {
+	// pinned on both sides , a callback with only an After constraint is sorted to the end of the chain
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().After("gorm:before_create").Before("gorm:save_before_associations").
+			Register("kboot:mixin_before_create", mixinHooksCallback(HookBeforeSave, HookBeforeCreate)),
+		cb.Create().After("gorm:after_create").Before("gorm:commit_or_rollback_transaction").
+			Register("kboot:mixin_after_create", mixinHooksCallback(HookAfterCreate, HookAfterSave)),
+		cb.Update().After("gorm:before_update").Before("gorm:save_before_associations").
+			Register("kboot:mixin_before_update", mixinHooksCallback(HookBeforeSave, HookBeforeUpdate)),
+		cb.Update().After("gorm:after_update").Before("gorm:commit_or_rollback_transaction").
+			Register("kboot:mixin_after_update", mixinHooksCallback(HookAfterUpdate, HookAfterSave)),
+		cb.Delete().After("gorm:before_delete").Before("gorm:delete_before_associations").
+			Register("kboot:mixin_before_delete", mixinHooksCallback(HookBeforeDelete)),
+		cb.Delete().After("gorm:after_delete").Before("gorm:commit_or_rollback_transaction").
+			Register("kboot:mixin_after_delete", mixinHooksCallback(HookAfterDelete)),
+		cb.Query().After("gorm:after_query").
+			Register("kboot:mixin_after_query", mixinHooksCallback(HookAfterFind)),
+	)
+}
+
+func mixinHooksCallback(hooks ...string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.Statement.Schema == nil || db.Statement.SkipHooks {
+			return
+		}
+		for _, hook := range hooks {
+			if modelHasHook(db.Statement.Schema, hook) {
+				continue
+			}
+			eachModel(db.Statement.ReflectValue, func(v reflect.Value) {
+				if err := runMixinHooks(db, v, hook); err != nil {
+					_ = db.AddError(err)
+				}
+			})
+		}
+	}
+}
+
+// eachModel call fn on every addressable struct of rv
+func eachModel(rv reflect.Value, fn func(v reflect.Value)) {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if elem.Kind() == reflect.Struct && elem.CanAddr() {
+				fn(elem)
+			}
+		}
+	case reflect.Struct:
+		if rv.CanAddr() {
+			fn(rv)
+		}
+	}
+}
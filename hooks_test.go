@@ -0,0 +1,66 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FailingMixin struct {
+	Fail bool `gorm:"-"`
+}
+
+func (this *FailingMixin) AfterFind(*gorm.DB) error {
+	if this.Fail {
+		return errors.New("mixin failed")
+	}
+	return nil
+}
+
+// article embeds several mixins with AfterSave/AfterFind and forwards nothing
+type article struct {
+	UuidPriWithCreateAtBase
+	UpdatedAt
+	Title string `gorm:"column:title"`
+}
+
+func (*article) TableName() string {
+	return "t_articles"
+}
+
+func TestMixinHooks(t *testing.T) {
+	orm := newTestORM(t, new(article))
+	var err error
+	a := &article{Title: "a1"}
+	if err = orm.Create(a).Error; err != nil {
+		t.Fatalf("create err %v", err)
+	}
+	if a.ID == "" || a.CreatedAtTs == 0 || a.UpdatedAtTs == 0 {
+		t.Fatalf("mixin hooks not run on create %+v", a)
+	}
+	found := make([]*article, 0)
+	if err = orm.Find(&found).Error; err != nil || len(found) != 1 {
+		t.Fatalf("find err %v", err)
+	}
+	if found[0].CreatedAtTs == 0 || found[0].UpdatedAtTs == 0 {
+		t.Fatalf("mixin hooks not run on find %+v", found[0])
+	}
+	// errors of all mixins are returned
+	m := &struct {
+		CreatedAt
+		FailingMixin
+	}{FailingMixin: FailingMixin{Fail: true}}
+	m.CreatedAt.CreatedAt = time.Unix(100, 0)
+	if err = RunMixinHooks(orm, m, HookAfterFind); err == nil || m.CreatedAtTs != 100 {
+		t.Fatalf("expect mixin error and CreatedAtTs set , got %v , %d", err, m.CreatedAtTs)
+	}
+	full := &struct {
+		UlidPriWithFullTsBase
+	}{}
+	full.UpdatedAt.UpdatedAt = time.Unix(200, 0)
+	if err = RunMixinHooks(orm, full, HookAfterFind); err != nil || full.UpdatedAtTs != 200 {
+		t.Fatalf("unexpected full ts base %+v , %v", full, err)
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 
@@ -38,9 +39,35 @@ func _init(unit kboot.Unit) (kboot.ExecFunc, error) {
 		return nil, merrors.Errorf("no valid db Config found")
 	}
 	gormLogger.Default = newTraceLogger(kboot.GetTaggedZapLogger(ModuleName), *cfgList[cfgKeyDefault])
+	SetCursorSecret([]byte(cfgList[cfgKeyDefault].CursorSecret))
+	if cfgList[cfgKeyDefault].CursorSecret == "" {
+		kboot.GetTaggedZapLogger(ModuleName).Warn("no cursorSecret configured , cursors are signed by a random key " +
+			"and fail across restarts and instances")
+	}
+	if node := cfgList[cfgKeyDefault].SnowflakeNode; node != nil {
+		err = SetSnowflakeNode(*node)
+	} else {
+		hostname, _ := os.Hostname()
+		derived := hostSnowflakeNode(hostname)
+		kboot.GetTaggedZapLogger(ModuleName).Warn("no snowflakeNode configured , derived it from the hostname , "+
+			"instances whose hostnames collide generate duplicated ids",
+			zap.String("hostname", hostname), zap.Int64("node", derived))
+		err = SetSnowflakeNode(derived)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = SetTimeFormat(cfgList[cfgKeyDefault].TimeFormat); err != nil {
+		return nil, err
+	}
+	SetActorRequired(cfgList[cfgKeyDefault].RequireActor)
 	for _, cfg := range cfgList {
 		ds := cfg.name
-		orm, err := newORM(unit.GetContext(), *cfg, timezone)
+		location, err := cfg.location(timezone)
+		if err != nil {
+			return nil, merrors.Errorf("init datasource '%s' err : %v", ds, err)
+		}
+		orm, err := newORM(unit.GetContext(), *cfg, location)
 		if err != nil {
 			return nil, merrors.Errorf("init datasource '%s' err : %v", ds, err)
 		}
@@ -68,6 +95,15 @@ func bindConfig() (map[string]*Config, error) {
 		kboot.MustBindEnv(cfgKeyDbType),
 		kboot.MustBindEnv(cfgKeyDbTimezone),
 		kboot.MustBindEnv(cfgKeyDbSlowThresholdMs),
+		kboot.MustBindEnv(cfgKeyDbLogLevel),
+		kboot.MustBindEnv(cfgKeyDbSampleRate),
+		kboot.MustBindEnv(cfgKeyDbRateLimit),
+		kboot.MustBindEnv(cfgKeyDbSampleSummary),
+		kboot.MustBindEnv(cfgKeyDbRecentQueries),
+		kboot.MustBindEnv(cfgKeyDbCursorSecret),
+		kboot.MustBindEnv(cfgKeyDbSnowflakeNode),
+		kboot.MustBindEnv(cfgKeyDbTimeFormat),
+		kboot.MustBindEnv(cfgKeyDbRequireActor),
 	)
 	if err != nil {
 		return nil, err
@@ -90,6 +126,11 @@ func bindConfig() (map[string]*Config, error) {
 				kboot.MustBindEnv(cfgKeyDbType),
 				kboot.MustBindEnv(cfgKeyDbTimezone),
 				kboot.MustBindEnv(cfgKeyDbSlowThresholdMs),
+				kboot.MustBindEnv(cfgKeyDbLogLevel),
+				kboot.MustBindEnv(cfgKeyDbSampleRate),
+				kboot.MustBindEnv(cfgKeyDbRateLimit),
+				kboot.MustBindEnv(cfgKeyDbSampleSummary),
+				kboot.MustBindEnv(cfgKeyDbRecentQueries),
 			); err != nil {
 				return nil, err
 			}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MatchMode how a key is matched by LIKE
+type MatchMode int
+
+const (
+	// MatchContains col LIKE '%key%'
+	MatchContains MatchMode = iota
+	// MatchPrefix col LIKE 'key%'
+	MatchPrefix
+	// MatchSuffix col LIKE '%key'
+	MatchSuffix
+)
+
+// keyMatchCol a column matched by PageRequest.Key
+type keyMatchCol struct {
+	col        string
+	mode       MatchMode
+	ignoreCase bool
+}
+
+var _likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escape the LIKE wildcards in s , use it with ESCAPE '\'
+func EscapeLike(s string) string {
+	return _likeEscaper.Replace(s)
+}
+
+// likePattern escape key and wrap it with wildcards by mode
+func likePattern(key string, mode MatchMode) string {
+	key = EscapeLike(key)
+	switch mode {
+	case MatchPrefix:
+		return key + "%"
+	case MatchSuffix:
+		return "%" + key
+	default:
+		return "%" + key + "%"
+	}
+}
+
+// likeCond build the LIKE condition of col , ILIKE is used on postgres when ignoreCase ,
+// LOWER() on both sides elsewhere
+func likeCond(tx *gorm.DB, col string, key string, mode MatchMode, ignoreCase bool) (string, interface{}) {
+	pattern := likePattern(key, mode)
+	if !ignoreCase {
+		return fmt.Sprintf(`%s LIKE ? ESCAPE '\'`, col), pattern
+	}
+	if tx.Dialector.Name() == DsTypePg {
+		return fmt.Sprintf(`%s ILIKE ? ESCAPE '\'`, col), pattern
+	}
+	return fmt.Sprintf(`LOWER(%s) LIKE ? ESCAPE '\'`, col), strings.ToLower(pattern)
+}
+
+// WithKeyMatchCol match PageRequest.Key on col with mode , case-insensitive when ignoreCase
+func WithKeyMatchCol(col string, mode MatchMode, ignoreCase bool) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.keyFuzzyCols = append(ctx.keyFuzzyCols, keyMatchCol{col: col, mode: mode, ignoreCase: ignoreCase})
+	})
+}
+
+// WithKeyFuzzyColsIgnoreCase same as WithKeyFuzzyCols but case-insensitive
+func WithKeyFuzzyColsIgnoreCase(col ...string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		for _, it := range col {
+			ctx.keyFuzzyCols = append(ctx.keyFuzzyCols, keyMatchCol{col: it, mode: MatchContains, ignoreCase: true})
+		}
+	})
+}
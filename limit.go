@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultMaxPageSize the max pageSize accepted by ParsePageRequest and BindPageRequest while the package
+// level PageLimits has no MaxPageSize , the queries themselves are unlimited by default
+const DefaultMaxPageSize = 1000
+
+// PageLimits protect the database from huge pages and deep paging
+type PageLimits struct {
+	// MaxPageSize max page size (or cursor limit) , also checked by ParsePageRequest and BindPageRequest ,
+	// 0 means unlimited (the binders then check DefaultMaxPageSize)
+	MaxPageSize int
+	// MaxOffset max rows skipped by (page-1)*pageSize , 0 means unlimited
+	MaxOffset int
+	// Clamp reduce an exceeding page size or page to the limits instead of failing with *PageLimitError
+	Clamp bool
+}
+
+var _pageLimits atomic.Pointer[PageLimits]
+
+func init() {
+	_pageLimits.Store(&PageLimits{})
+}
+
+// SetPageLimits change the package level limits , the default of every query
+func SetPageLimits(limits PageLimits) {
+	_pageLimits.Store(&limits)
+}
+
+// GetPageLimits the package level limits
+func GetPageLimits() PageLimits {
+	return *_pageLimits.Load()
+}
+
+// WithMaxPageSize override the package level MaxPageSize , 0 means unlimited
+func WithMaxPageSize(n int) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.limits.MaxPageSize = n
+	})
+}
+
+// WithMaxOffset override the package level MaxOffset , 0 means unlimited
+func WithMaxOffset(n int) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.limits.MaxOffset = n
+	})
+}
+
+// WithPageLimitClamp override the package level Clamp
+func WithPageLimitClamp(clamp bool) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.limits.Clamp = clamp
+	})
+}
+
+// PageLimitError the page exceeds a limit , Field is pageSize , limit or offset
+type PageLimitError struct {
+	Field string `json:"field"`
+	Value int    `json:"value"`
+	Max   int    `json:"max"`
+}
+
+func (this *PageLimitError) Error() string {
+	return fmt.Sprintf("%s %d exceeds the max %d", this.Field, this.Value, this.Max)
+}
+
+// limitSize check size against MaxPageSize , field names the size in the error
+func (ctx *pageCtx) limitSize(field string, size int) (int, error) {
+	max := ctx.limits.MaxPageSize
+	if max <= 0 || size <= max {
+		return size, nil
+	}
+	if ctx.limits.Clamp {
+		return max, nil
+	}
+	return 0, &PageLimitError{Field: field, Value: size, Max: max}
+}
+
+// limitPage check page against the limits , an exceeding page is clamped to the last page allowed
+// or rejected
+func (ctx *pageCtx) limitPage(page PageRequest) (PageRequest, error) {
+	pageSize, err := ctx.limitSize("pageSize", page.PageSizeV())
+	if err != nil {
+		return page, err
+	}
+	page.PageSize = &pageSize
+	max := ctx.limits.MaxOffset
+	if max <= 0 || page.Offset() <= max {
+		return page, nil
+	}
+	if !ctx.limits.Clamp {
+		return page, &PageLimitError{Field: "offset", Value: page.Offset(), Max: max}
+	}
+	pageV := max/pageSize + 1
+	page.Page = &pageV
+	return page, nil
+}
@@ -6,6 +6,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/guestin/log"
@@ -31,7 +33,74 @@ const (
 	YellowBold  = "\033[33;1m"
 )
 
-func newTraceLogger(rootLogger log.ZapLog, config Config) gormLogger.Interface {
+// _ormLoggers holds the trace logger of every datasource , used to switch log level at runtime
+var _ormLoggers = new(sync.Map)
+
+// SetLogLevel change the sql log level of datasource ds at runtime ,
+// empty ds means the default one , level must be one of silent,error,warn,info
+func SetLogLevel(ds string, level string) error {
+	lv, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	l, err := getTraceLogger(ds)
+	if err != nil {
+		return err
+	}
+	l.level.Store(int32(lv))
+	return nil
+}
+
+// GetLogLevel get the current sql log level of datasource ds , empty ds means the default one
+func GetLogLevel(ds string) (string, error) {
+	l, err := getTraceLogger(ds)
+	if err != nil {
+		return "", err
+	}
+	return logLevelString(l.logLevel()), nil
+}
+
+func getTraceLogger(ds string) (*traceLogger, error) {
+	if ds == "" {
+		ds = cfgKeyDefault
+	}
+	l, ok := _ormLoggers.Load(strings.ToLower(ds))
+	if !ok {
+		return nil, errors.Errorf("no such db '%s' configured", ds)
+	}
+	return l.(*traceLogger), nil
+}
+
+func parseLogLevel(level string) (gormLogger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case LogLevelSilent:
+		return gormLogger.Silent, nil
+	case LogLevelError:
+		return gormLogger.Error, nil
+	case LogLevelWarn:
+		return gormLogger.Warn, nil
+	case LogLevelInfo:
+		return gormLogger.Info, nil
+	default:
+		return 0, errors.Errorf("unknown log level '%s' , must be one of [%s,%s,%s,%s]", level,
+			LogLevelSilent, LogLevelError, LogLevelWarn, LogLevelInfo)
+	}
+}
+
+func logLevelString(level gormLogger.LogLevel) string {
+	switch level {
+	case gormLogger.Silent:
+		return LogLevelSilent
+	case gormLogger.Error:
+		return LogLevelError
+	case gormLogger.Info:
+		return LogLevelInfo
+	default:
+		return LogLevelWarn
+	}
+}
+
+func newTraceLogger(rootLogger log.ZapLog, config Config) *traceLogger {
 	if config.SlowThresholdMs == 0 {
 		config.SlowThresholdMs = 200
 	}
@@ -39,6 +108,14 @@ func newTraceLogger(rootLogger log.ZapLog, config Config) gormLogger.Interface {
 		config.Colorful = new(bool)
 		*config.Colorful = true
 	}
+	level := gormLogger.Warn
+	if config.Debug {
+		level = gormLogger.Info
+	}
+	if config.LogLevel != "" {
+		// already checked by config validation
+		level, _ = parseLogLevel(config.LogLevel)
+	}
 	var (
 		infoStr      = "%s\n"
 		warnStr      = "%s\n"
@@ -56,14 +133,17 @@ func newTraceLogger(rootLogger log.ZapLog, config Config) gormLogger.Interface {
 		traceWarnStr = Green + "%s " + Yellow + "%s" + Reset + "\n" + RedBold + "[%.3fms] " + Yellow + "[rows:%v]" + Magenta + " %s" + Reset
 		traceErrStr = RedBold + "%s " + MagentaBold + "%s" + Reset + "\n" + Yellow + "[%.3fms] " + BlueBold + "[rows:%v]" + Reset + " %s"
 	}
-	return &traceLogger{
+	ret := &traceLogger{
 		Config: gormLogger.Config{
 			SlowThreshold:             time.Millisecond * time.Duration(config.SlowThresholdMs),
 			Colorful:                  *config.Colorful,
 			IgnoreRecordNotFoundError: false,
 			ParameterizedQueries:      false,
-			LogLevel:                  gormLogger.Warn,
+			LogLevel:                  level,
 		},
+		level:        new(atomic.Int32),
+		sampler:      newLogSampler(config),
+		recorder:     newQueryRecorder(config.RecentQueries),
 		zapLogger:    rootLogger.With(log.WithZapOptions(zap.WithCaller(false))),
 		config:       config,
 		infoStr:      infoStr,
@@ -73,39 +153,59 @@ func newTraceLogger(rootLogger log.ZapLog, config Config) gormLogger.Interface {
 		traceWarnStr: traceWarnStr,
 		traceErrStr:  traceErrStr,
 	}
+	if config.Type == DsTypeSqlLite {
+		ret.quote = '"'
+	} else {
+		ret.quote = '\''
+	}
+	ret.level.Store(int32(level))
+	return ret
 }
 
 type traceLogger struct {
 	gormLogger.Config
+	// level is shared by all sessions of the datasource , so it can be switched at runtime
+	level                               *atomic.Int32
+	sampler                             *logSampler
+	recorder                            *queryRecorder
 	config                              Config
 	zapLogger                           log.ZapLog
 	infoStr, warnStr, errStr            string
 	traceStr, traceErrStr, traceWarnStr string
+	// quote the dialect interpolates string values with , see redactSQL
+	quote byte
 }
 
 func (l *traceLogger) LogMode(level gormLogger.LogLevel) gormLogger.Interface {
 	newLogger := *l
 	newLogger.LogLevel = level
+	// explicit LogMode (e.g. db.Debug()) detach from the runtime level
+	newLogger.level = new(atomic.Int32)
+	newLogger.level.Store(int32(level))
 	return &newLogger
 }
 
+func (l *traceLogger) logLevel() gormLogger.LogLevel {
+	return gormLogger.LogLevel(l.level.Load())
+}
+
 // Info print info
 func (l *traceLogger) Info(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= gormLogger.Info {
+	if l.logLevel() >= gormLogger.Info {
 		l.Printf(ctx, gormLogger.Info, l.infoStr+msg, append([]interface{}{fileWithLineNum(ctx)}, data...)...)
 	}
 }
 
 // Warn print warn messages
 func (l *traceLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= gormLogger.Warn {
+	if l.logLevel() >= gormLogger.Warn {
 		l.Printf(ctx, gormLogger.Warn, l.warnStr+msg, append([]interface{}{fileWithLineNum(ctx)}, data...)...)
 	}
 }
 
 // Error print error messages
 func (l *traceLogger) Error(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= gormLogger.Error {
+	if l.logLevel() >= gormLogger.Error {
 		l.Printf(ctx, gormLogger.Error, l.errStr+msg, append([]interface{}{fileWithLineNum(ctx)}, data...)...)
 	}
 }
@@ -121,25 +221,86 @@ func (l *traceLogger) rowStr(row int64) string {
 //
 //nolint:cyclop
 func (l *traceLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
-	if l.LogLevel <= gormLogger.Silent {
+	elapsed := time.Since(begin)
+	if l.recorder != nil {
+		fc = l.record(ctx, elapsed, fc, err)
+	}
+	if l.sampler != nil {
+		// reported whatever the level , so drops before the level was lowered are not lost
+		l.reportDropped()
+	}
+	level := l.logLevel()
+	if level <= gormLogger.Silent {
 		return
 	}
 
-	elapsed := time.Since(begin)
 	switch {
-	case err != nil && l.LogLevel >= gormLogger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.IgnoreRecordNotFoundError):
+	case err != nil && level >= gormLogger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.IgnoreRecordNotFoundError):
 		sql, rows := fc()
 		l.Printf(ctx, gormLogger.Warn, l.traceErrStr, fileWithLineNum(ctx), err, float64(elapsed.Nanoseconds())/1e6, l.rowStr(rows), sql)
-	case elapsed > l.SlowThreshold && l.SlowThreshold != 0 && l.LogLevel >= gormLogger.Warn:
+	case elapsed > l.SlowThreshold && l.SlowThreshold != 0 && level >= gormLogger.Warn:
 		sql, rows := fc()
 		slowLog := fmt.Sprintf("SLOW SQL >= %v", l.SlowThreshold)
 		l.Printf(ctx, gormLogger.Warn, l.traceWarnStr, fileWithLineNum(ctx), slowLog, float64(elapsed.Nanoseconds())/1e6, l.rowStr(rows), sql)
-	case l.LogLevel == gormLogger.Info:
+	case level == gormLogger.Info:
+		if l.sampler != nil {
+			fc = memoSQL(fc)
+			if !l.sampler.allow(func() string {
+				sql, _ := fc()
+				return sqlFingerprint(sql, l.quote)
+			}) {
+				return
+			}
+		}
 		sql, rows := fc()
 		l.Printf(ctx, gormLogger.Info, l.traceStr, fileWithLineNum(ctx), float64(elapsed.Nanoseconds())/1e6, l.rowStr(rows), sql)
 	}
 }
 
+// memoSQL a fc evaluating the statement once
+func memoSQL(fc func() (string, int64)) func() (string, int64) {
+	var (
+		sql  string
+		rows int64
+		done bool
+	)
+	return func() (string, int64) {
+		if !done {
+			sql, rows = fc()
+			done = true
+		}
+		return sql, rows
+	}
+}
+
+// record capture the statement into the recorder , return a fc reusing the captured sql
+func (l *traceLogger) record(ctx context.Context, elapsed time.Duration, fc func() (string, int64), err error) func() (string, int64) {
+	sql, rows := fc()
+	record := QueryRecord{
+		Time:       time.Now(),
+		Datasource: l.config.name,
+		SQL:        redactSQL(sql, l.quote),
+		DurationMs: float64(elapsed.Nanoseconds()) / 1e6,
+		Rows:       rows,
+		TraceId:    _traceId(ctx),
+		Caller:     fileWithLineNum(ctx),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	l.recorder.add(record)
+	return func() (string, int64) {
+		return sql, rows
+	}
+}
+
+func (l *traceLogger) reportDropped() {
+	dropped, elapsed, ok := l.sampler.summary()
+	if ok {
+		l.zapLogger.Info(fmt.Sprintf("sql log sampling dropped %d entries in last %v", dropped, elapsed.Round(time.Second)))
+	}
+}
+
 func (l *traceLogger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
 	if l.Config.ParameterizedQueries {
 		return sql, nil
@@ -153,6 +314,9 @@ func (l *traceLogger) Printf(ctx context.Context, lv gormLogger.LogLevel, s stri
 	if traceId != "" {
 		logger = logger.With(log.UseSubTag(log.NewFixStyleText(traceId, log.Blue, false)))
 	}
+	if fields := _logFields(ctx); len(fields) > 0 {
+		logger = logger.With(log.UseFields(fields...))
+	}
 	switch lv {
 	case gormLogger.Error:
 		lines := strings.Split(fmt.Sprintf(s, i...), "\n")
@@ -184,6 +348,16 @@ func _traceId(ctx context.Context) string {
 	return ""
 }
 
+func _logFields(ctx context.Context) []zap.Field {
+	if ctx != nil {
+		i := ctx.Value(CtxLogFieldsKey)
+		if i != nil {
+			return i.([]zap.Field)
+		}
+	}
+	return nil
+}
+
 func _traceSkip(ctx context.Context) int {
 	i := ctx.Value(CtxTraceSkipKey)
 	if i != nil {
@@ -192,19 +366,97 @@ func _traceSkip(ctx context.Context) int {
 	return 0
 }
 
-// // FileWithLineNum return the file name and line number of the current file
+const (
+	// callerDepth initial frames captured when looking for the caller of a statement
+	callerDepth = 64
+	// maxCallerDepth frames captured at most for very deep call stacks
+	maxCallerDepth = 1024
+)
+
+var (
+	_callerSkipPackages atomic.Pointer[[]string]
+	_keepTestFrames     atomic.Bool
+)
+
+// AddCallerSkipPackages register package path prefixes (e.g. "github.com/foo/bar/dao") whose frames
+// are skipped when attributing the caller of a sql statement , so the business code calling
+// your repository/dao packages is logged instead of the dao itself
+func AddCallerSkipPackages(pkgPath ...string) {
+	for {
+		old := _callerSkipPackages.Load()
+		all := make([]string, 0)
+		if old != nil {
+			all = append(all, *old...)
+		}
+		for _, p := range pkgPath {
+			p = strings.TrimSuffix(p, "/")
+			if p != "" {
+				all = append(all, p)
+			}
+		}
+		if _callerSkipPackages.CompareAndSwap(old, &all) {
+			return
+		}
+	}
+}
+
+// KeepCallerTestFrames when keep is true , _test.go frames inside the packages registered by
+// AddCallerSkipPackages are attributed as the caller instead of being skipped
+func KeepCallerTestFrames(keep bool) {
+	_keepTestFrames.Store(keep)
+}
+
+// inSkipPackages report whether the frame belongs to one of the registered packages
+func inSkipPackages(frame runtime.Frame) bool {
+	pkgs := _callerSkipPackages.Load()
+	if pkgs == nil {
+		return false
+	}
+	for _, pkg := range *pkgs {
+		if strings.HasPrefix(frame.Function, pkg) && len(frame.Function) > len(pkg) {
+			// match package boundary only , "foo/dao" must not match "foo/daox"
+			switch frame.Function[len(pkg)] {
+			case '.', '/':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isCallerFrame(frame runtime.Frame) bool {
+	if strings.HasSuffix(frame.File, ".gen.go") {
+		return false
+	}
+	isTest := strings.HasSuffix(frame.File, "_test.go")
+	if inSkipPackages(frame) {
+		return isTest && _keepTestFrames.Load()
+	}
+	return (!strings.HasPrefix(frame.File, _sourceDir) && !strings.Contains(frame.File, "gorm.io")) || isTest
+}
+
+// fileWithLineNum return the file name and line number of the statement caller
 func fileWithLineNum(ctx context.Context) string {
-	pcs := [13]uintptr{}
 	// the third caller usually from gorm internal
-	l := runtime.Callers(5+_traceSkip(ctx), pcs[:])
+	skip := 5 + _traceSkip(ctx)
+	pcs := make([]uintptr, callerDepth)
+	l := runtime.Callers(skip, pcs)
+	for l == len(pcs) && len(pcs) < maxCallerDepth {
+		pcs = make([]uintptr, len(pcs)*2)
+		l = runtime.Callers(skip, pcs)
+	}
+	if l == 0 {
+		return ""
+	}
 	frames := runtime.CallersFrames(pcs[:l])
-	for i := 0; i < l; i++ {
-		// second return value is "more", not "ok"
-		frame, _ := frames.Next()
-		if ((!strings.HasPrefix(frame.File, _sourceDir) && !strings.Contains(frame.File, "gorm.io")) ||
-			strings.HasSuffix(frame.File, "_test.go")) && !strings.HasSuffix(frame.File, ".gen.go") {
+	for {
+		frame, more := frames.Next()
+		if isCallerFrame(frame) {
 			return string(strconv.AppendInt(append([]byte(frame.File), ':'), int64(frame.Line), 10))
 		}
+		if !more {
+			break
+		}
 	}
 
 	return ""
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guestin/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// newObservedLogger a colorless trace logger of config writing into the returned logs
+func newObservedLogger(config Config) (*traceLogger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	config.Colorful = new(bool)
+	return newTraceLogger(log.NewTaggedZapLogger(zap.New(core), ModuleName), config), logs
+}
+
+// sqlOf a Trace fc of sql
+func sqlOf(sql string) func() (string, int64) {
+	return func() (string, int64) {
+		return sql, 1
+	}
+}
+
+// countLogs the entries containing s
+func countLogs(logs *observer.ObservedLogs, s string) int {
+	return len(logs.FilterMessageSnippet(s).All())
+}
+
+func TestLogSampling(t *testing.T) {
+	l, logs := newObservedLogger(Config{Type: DsTypeSqlLite, LogLevel: LogLevelInfo, SampleRate: 2})
+	for i := 0; i < 10; i++ {
+		l.Trace(context.Background(), time.Now(), sqlOf("SELECT 1"), nil)
+	}
+	if n := countLogs(logs, "SELECT 1"); n != 5 {
+		t.Fatalf("expect 5 of 10 statements logged , got %d", n)
+	}
+}
+
+func TestLogRateLimit(t *testing.T) {
+	l, logs := newObservedLogger(Config{Type: DsTypeSqlLite, LogLevel: LogLevelInfo, RateLimit: 2})
+	second := time.Now().Unix()
+	// one call site running two statements , the limit applies to each statement
+	for i := 0; i < 5; i++ {
+		l.Trace(context.Background(), time.Now(), sqlOf(fmt.Sprintf(`SELECT * FROM t_users WHERE id = "u%d"`, i)), nil)
+		l.Trace(context.Background(), time.Now(), sqlOf(fmt.Sprintf("SELECT * FROM t_pets WHERE age > %d", i)), nil)
+	}
+	if time.Now().Unix() != second {
+		t.Skip("crossed a second boundary")
+	}
+	if n, m := countLogs(logs, "t_users"), countLogs(logs, "t_pets"); n != 2 || m != 2 {
+		t.Fatalf("expect 2 entries per statement , got %d and %d", n, m)
+	}
+	// drops are reported after the level was lowered
+	l.level.Store(int32(gormLogger.Warn))
+	l.sampler.lastSummary.Store(time.Now().Add(-time.Hour).UnixNano())
+	l.Trace(context.Background(), time.Now(), sqlOf("SELECT 1"), nil)
+	if n := countLogs(logs, "sql log sampling dropped 6 entries"); n != 1 {
+		t.Fatalf("expect the dropped summary , got %d", n)
+	}
+}
+
+func TestSqlFingerprint(t *testing.T) {
+	cases := []struct {
+		sql   string
+		quote byte
+		want  string
+	}{
+		{`SELECT * FROM "t_users" WHERE name = 'it''s' AND age > 18`, '\'', `SELECT * FROM "t_users" WHERE name = ? AND age > ?`},
+		{"SELECT * FROM `t_users2` WHERE id IN (\"a\",\"b\",\"c\") LIMIT 10", '"', "SELECT * FROM `t_users2` WHERE id IN (?) LIMIT ?"},
+		{`UPDATE t SET score = 1.5 , deleted_at = NULL WHERE id = -3`, '\'', `UPDATE t SET score = ? , deleted_at = NULL WHERE id = -?`},
+	}
+	for _, c := range cases {
+		if got := sqlFingerprint(c.sql, c.quote); got != c.want {
+			t.Fatalf("fingerprint of %s , expect %s , got %s", c.sql, c.want, got)
+		}
+	}
+	if !strings.Contains(redactSQL(`id IN ('a','b')`, '\''), "IN (?,?)") {
+		t.Fatalf("redact should keep the IN list")
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	l, logs := newObservedLogger(Config{Type: DsTypeSqlLite, LogLevel: LogLevelInfo})
+	_ormLoggers.Store("log_level_test", l)
+	t.Cleanup(func() {
+		_ormLoggers.Delete("log_level_test")
+	})
+	if lv, err := GetLogLevel("log_level_test"); err != nil || lv != LogLevelInfo {
+		t.Fatalf("expect info level , got %s %v", lv, err)
+	}
+	// explicit LogMode keeps its own level
+	detached := l.LogMode(gormLogger.Info).(*traceLogger)
+	if err := SetLogLevel("LOG_LEVEL_TEST", LogLevelError); err != nil {
+		t.Fatalf("set level err %v", err)
+	}
+	if lv, _ := GetLogLevel("log_level_test"); lv != LogLevelError {
+		t.Fatalf("expect error level , got %s", lv)
+	}
+	l.Trace(context.Background(), time.Now(), sqlOf("SELECT 1"), nil)
+	detached.Trace(context.Background(), time.Now(), sqlOf("SELECT 2"), nil)
+	if countLogs(logs, "SELECT 1") != 0 || countLogs(logs, "SELECT 2") != 1 {
+		t.Fatalf("unexpected logs %v", logs.All())
+	}
+	if err := SetLogLevel("log_level_test", "verbose"); err == nil {
+		t.Fatalf("expect unknown level error")
+	}
+	if _, err := GetLogLevel("no_such_db"); err == nil {
+		t.Fatalf("expect unknown db error")
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	l, logs := newObservedLogger(Config{Type: DsTypeSqlLite, LogLevel: LogLevelInfo})
+	ctx := WithLogFields(context.Background(), zap.String("tenantId", "t1"))
+	orm := newTestORM(t).Session(&gorm.Session{Logger: l, Context: ctx})
+	// fields of the option are appended to the ones already in the context
+	orm = Wrap(orm, LogFields(zap.String("userId", "u1")), TraceId("trace1"))
+	if fields := _logFields(orm.Statement.Context); len(fields) != 2 {
+		t.Fatalf("expect 2 merged fields , got %v", fields)
+	}
+	orm.Exec("SELECT 1")
+	entries := logs.FilterMessageSnippet("SELECT 1").All()
+	if len(entries) != 1 {
+		t.Fatalf("expect 1 entry , got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["tenantId"] != "t1" || fields["userId"] != "u1" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+	if WithLogFields(ctx) != ctx {
+		t.Fatalf("no fields should keep the context")
+	}
+}
+
+func TestCallerSkipPackages(t *testing.T) {
+	old := _callerSkipPackages.Load()
+	t.Cleanup(func() {
+		_callerSkipPackages.Store(old)
+		KeepCallerTestFrames(false)
+	})
+	dao := runtime.Frame{Function: "github.com/foo/bar/dao.(*UserDao).Find", File: "/src/bar/dao/user.go"}
+	daox := runtime.Frame{Function: "github.com/foo/bar/daox.Find", File: "/src/bar/daox/user.go"}
+	daoTest := runtime.Frame{Function: "github.com/foo/bar/dao.TestFind", File: "/src/bar/dao/user_test.go"}
+	if !isCallerFrame(dao) {
+		t.Fatalf("frame outside the module should be the caller")
+	}
+	AddCallerSkipPackages("github.com/foo/bar/dao/")
+	if isCallerFrame(dao) || !isCallerFrame(daox) || isCallerFrame(daoTest) {
+		t.Fatalf("only the frames of the dao package should be skipped")
+	}
+	KeepCallerTestFrames(true)
+	if !isCallerFrame(daoTest) {
+		t.Fatalf("test frames of the dao package should be kept")
+	}
+
+	// end to end , the test file is attributed until its package is skipped
+	l, logs := newObservedLogger(Config{Type: DsTypeSqlLite, LogLevel: LogLevelInfo})
+	orm := newTestORM(t).Session(&gorm.Session{Logger: l})
+	KeepCallerTestFrames(false)
+	orm.Exec("SELECT 1")
+	AddCallerSkipPackages("github.com/guestin/kboot-db-starter")
+	orm.Exec("SELECT 2")
+	if n := countLogs(logs, "logger_test.go:"); n != 1 {
+		t.Fatalf("expect 1 entry attributed to the test , got %d : %v", n, logs.All())
+	}
+}
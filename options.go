@@ -1,10 +1,14 @@
 package db
 
+import "go.uber.org/zap"
+
 type (
 	_ormCxt struct {
 		dbSelect   string
 		traceId    string
 		callerSkip int
+		logFields  []zap.Field
+		actor      string
 	}
 	Option interface {
 		apply(ctx *_ormCxt)
@@ -33,3 +37,17 @@ func CallerSkip(skip int) Option {
 		ctx.callerSkip = skip
 	})
 }
+
+// LogFields attach fields to every sql log entry emitted through the returned db
+func LogFields(fields ...zap.Field) Option {
+	return optionFunc(func(ctx *_ormCxt) {
+		ctx.logFields = append(ctx.logFields, fields...)
+	})
+}
+
+// Actor set the actor (e.g. the current user id) recorded by the audit mixins
+func Actor(id string) Option {
+	return optionFunc(func(ctx *_ormCxt) {
+		ctx.actor = id
+	})
+}
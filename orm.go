@@ -9,6 +9,7 @@ import (
 
 	"github.com/guestin/kboot"
 	"github.com/ooopSnake/assert.go"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -49,14 +50,7 @@ func ORM(o ...Option) *gorm.DB {
 		opt.apply(ctx)
 	}
 	ins := getDB(ctx.dbSelect)
-	insCtx := ins.Statement.Context
-	if ctx.traceId != "" {
-		insCtx = context.WithValue(insCtx, CtxTraceIdKey, ctx.traceId)
-	}
-	if ctx.callerSkip > 0 {
-		insCtx = context.WithValue(insCtx, CtxTraceSkipKey, ctx.callerSkip)
-	}
-	return ins.WithContext(insCtx)
+	return ins.WithContext(ctx.wrapContext(ins.Statement.Context))
 }
 
 // Wrap an existing gorm.DB with options like traceId , callerSkip
@@ -69,24 +63,46 @@ func Wrap(orm *gorm.DB, o ...Option) *gorm.DB {
 	for _, opt := range o {
 		opt.apply(ctx)
 	}
-	insCtx := orm.Statement.Context
-	if ctx.traceId != "" {
-		insCtx = context.WithValue(insCtx, CtxTraceIdKey, ctx.traceId)
+	return orm.WithContext(ctx.wrapContext(orm.Statement.Context))
+}
+
+func (this *_ormCxt) wrapContext(insCtx context.Context) context.Context {
+	if this.traceId != "" {
+		insCtx = context.WithValue(insCtx, CtxTraceIdKey, this.traceId)
+	}
+	if this.callerSkip > 0 {
+		insCtx = context.WithValue(insCtx, CtxTraceSkipKey, this.callerSkip)
+	}
+	if len(this.logFields) > 0 {
+		insCtx = WithLogFields(insCtx, this.logFields...)
+	}
+	if this.actor != "" {
+		insCtx = WithActor(insCtx, this.actor)
 	}
-	if ctx.callerSkip > 0 {
-		insCtx = context.WithValue(insCtx, CtxTraceSkipKey, ctx.callerSkip)
+	return insCtx
+}
+
+// WithLogFields return a copy of ctx carrying fields , which will be attached to every sql log entry
+// emitted with the context , fields already in ctx are kept
+func WithLogFields(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
 	}
-	return orm.WithContext(insCtx)
+	exist := _logFields(ctx)
+	all := make([]zap.Field, 0, len(exist)+len(fields))
+	all = append(append(all, exist...), fields...)
+	return context.WithValue(ctx, CtxLogFieldsKey, all)
 }
 
 func newORM(ctx context.Context, config Config, location *time.Location) (*gorm.DB, error) {
 	var dbDialer func(dsn string) gorm.Dialector
+	logger := newTraceLogger(kboot.GetTaggedZapLogger(ModuleName), config)
 	dbConfig := &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
 		NowFunc: func() time.Time {
 			return time.Now().In(location)
 		},
-		Logger: newTraceLogger(kboot.GetTaggedZapLogger(ModuleName), config),
+		Logger: logger,
 	}
 	switch config.Type {
 	case DsTypeSqlLite:
@@ -98,9 +114,20 @@ func newORM(ctx context.Context, config Config, location *time.Location) (*gorm.
 	if err != nil {
 		return nil, err
 	}
-	if config.Debug {
-		orm = orm.Debug()
+	// run the hooks of embedded mixins which are ambiguous on the model
+	if err = orm.Use(mixinHooksPlugin{}); err != nil {
+		return nil, err
+	}
+	// the audit mixins are filled by callbacks , their hooks would be shadowed by the primary key mixins
+	if err = orm.Use(auditPlugin{}); err != nil {
+		return nil, err
+	}
+	// the timestamp columns found are rendered in the timezone of the datasource
+	if err = orm.Use(timeLocationPlugin{location: location}); err != nil {
+		return nil, err
 	}
+	// debug is handled by the logger level , so it can be switched later by SetLogLevel
+	_ormLoggers.Store(config.name, logger)
 	// assign context
 	orm = orm.WithContext(ctx)
 	return orm, nil
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newTestORM open a sqlite datasource in a temp dir of the test and migrate models ,
+// the datasource is closed and removed when the test ends
+func newTestORM(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	return newTestORMIn(t, time.Local, models...)
+}
+
+// newTestORMIn newTestORM with the datasource in timezone location
+func newTestORMIn(t *testing.T, location *time.Location, models ...interface{}) *gorm.DB {
+	t.Helper()
+	cfg := Config{
+		name: t.Name(),
+		Type: DsTypeSqlLite,
+		DSN:  filepath.Join(t.TempDir(), "test.db"),
+	}
+	orm, err := newORM(context.Background(), cfg, location)
+	if err != nil {
+		t.Fatalf("new orm err %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := orm.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	if err = orm.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate err %v", err)
+	}
+	return orm
+}
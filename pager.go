@@ -8,21 +8,30 @@ import (
 	"github.com/ooopSnake/assert.go"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
 )
 
 type PageRequest struct {
-	Page     *int `json:"page" query:"page" form:"page" path:"page" form:"page" validate:"omitempty,gt=0"`
-	PageSize *int `json:"pageSize" query:"pageSize" form:"pageSize" path:"pageSize" form:"pageSize" validate:"omitempty,gt=0"`
+	Page     *int `json:"page" query:"page" form:"page" path:"page" validate:"omitempty,gt=0"`
+	PageSize *int `json:"pageSize" query:"pageSize" form:"pageSize" path:"pageSize" validate:"omitempty,gt=0"`
 
-	Begin int64  `json:"begin" query:"begin" form:"begin" path:"begin" form:"begin" validate:"gte=0"`
-	End   *int64 `json:"end" query:"end" form:"end" path:"end" form:"end" validate:"omitempty,gtefield=Begin"`
+	Begin int64  `json:"begin" query:"begin" form:"begin" path:"begin" validate:"gte=0"`
+	End   *int64 `json:"end" query:"end" form:"end" path:"end" validate:"omitempty,gtefield=Begin"`
 
-	Key string `json:"key" query:"key" form:"key" path:"key" form:"key"`
+	Key string `json:"key" query:"key" form:"key" path:"key"`
 
-	OrderBy string `json:"orderBy" query:"orderBy" form:"orderBy" path:"orderBy" form:"orderBy"`
+	// OrderBy comma separated sort params , prefix '-' for DESC and '+' for ASC ,
+	// params without prefix follow Reserve , e.g. "age,-createdAt"
+	OrderBy string `json:"orderBy" query:"orderBy" form:"orderBy" path:"orderBy"`
 
 	Reserve bool `json:"reserve" query:"reserve" form:"reserve"`
+
+	// Fields comma separated fields to select , checked by WithSelectableFields , empty means all
+	Fields string `json:"fields" query:"fields" form:"fields"`
+
+	// Filter field filters , checked by WithFilterableCols , see ParseFilter
+	Filter Filter `json:"filter" query:"filter" form:"filter"`
 }
 
 func (this PageRequest) PageV() int {
@@ -60,6 +69,126 @@ func (this PageRequest) OrderV() string {
 	return "ASC"
 }
 
+// orderItem a checked sort column
+type orderItem struct {
+	param string
+	col   string
+	desc  bool
+	// vars of col when it is an expression , e.g. the full text rank
+	vars []interface{}
+}
+
+func (this orderItem) String() string {
+	if this.desc {
+		return fmt.Sprintf("%s DESC", this.col)
+	}
+	return fmt.Sprintf("%s ASC", this.col)
+}
+
+// parseOrderBy split orderBy into sort columns , each one must be in the colLimit whitelist
+func parseOrderBy(orderBy string, reserve bool, colLimit map[string]string) ([]orderItem, error) {
+	ret := make([]orderItem, 0)
+	seen := make(map[string]bool)
+	for _, param := range strings.Split(orderBy, ",") {
+		param = strings.TrimSpace(param)
+		desc := reserve
+		switch {
+		case strings.HasPrefix(param, "-"):
+			desc = true
+			param = param[1:]
+		case strings.HasPrefix(param, "+"):
+			desc = false
+			param = param[1:]
+		}
+		if len(param) == 0 {
+			continue
+		}
+		orderCol, ok := colLimit[param]
+		if !ok {
+			return nil, errors.Errorf("orderBy '%s' not allowed , must be one of [%s]", param,
+				mkArrayString(colLimit))
+		}
+		if seen[param] {
+			return nil, errors.Errorf("duplicate orderBy '%s'", param)
+		}
+		seen[param] = true
+		ret = append(ret, orderItem{param: param, col: orderCol, desc: desc})
+	}
+	return ret, nil
+}
+
+// modelSchema parse the gorm schema of model m
+func modelSchema(tx *gorm.DB, m interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(m); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// withPkTiebreaker append the primary key to orders , so rows with equal sort values keep a stable order
+func withPkTiebreaker(orders []orderItem, s *schema.Schema) []orderItem {
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return orders
+	}
+	desc := false
+	for _, item := range orders {
+		col := strings.TrimPrefix(item.col, s.Table+".")
+		if col == pk.DBName || s.LookUpField(col) == pk {
+			return orders
+		}
+		desc = item.desc
+	}
+	return append(orders, orderItem{param: pk.Name, col: fmt.Sprintf("%s.%s", s.Table, pk.DBName), desc: desc})
+}
+
+// timeCol the begin end column , WithBeginEndCol or the time typed CreatedAt field of the model
+func (ctx *pageCtx) timeCol(s *schema.Schema) (string, error) {
+	if ctx.beginEndCol != "" {
+		table, col, ok := strings.Cut(ctx.beginEndCol, ".")
+		if !ok {
+			table, col = s.Table, ctx.beginEndCol
+		}
+		// columns of joined tables are not checked
+		if table == s.Table && s.LookUpField(col) == nil {
+			return "", errors.Errorf("begin end column '%s' not found in model '%s'", ctx.beginEndCol, s.Name)
+		}
+		return ctx.beginEndCol, nil
+	}
+	field := s.LookUpField("CreatedAt")
+	if field == nil || field.DataType != schema.Time {
+		field = nil
+		for _, it := range s.Fields {
+			if it.AutoCreateTime != 0 && it.DataType == schema.Time && it.DBName != "" {
+				field = it
+				break
+			}
+		}
+	}
+	if field == nil {
+		return "", errors.Errorf("model '%s' has no CreatedAt field for begin/end , set the column by WithBeginEndCol",
+			s.Name)
+	}
+	ctx.beginEndCol = fmt.Sprintf("%s.%s", s.Table, field.DBName)
+	return ctx.beginEndCol, nil
+}
+
+// defaultOrders the orders used when orderBy is empty , WithDefaultOrder , the begin end column DESC ,
+// or the primary key DESC
+func (ctx *pageCtx) defaultOrders(s *schema.Schema) []orderItem {
+	if len(ctx.defaultOrder) > 0 {
+		return ctx.defaultOrder
+	}
+	if col, err := ctx.timeCol(s); err == nil {
+		return []orderItem{{col: col, desc: true}}
+	}
+	if pk := s.PrioritizedPrimaryField; pk != nil {
+		return []orderItem{{param: pk.Name, col: fmt.Sprintf("%s.%s", s.Table, pk.DBName), desc: true}}
+	}
+	return nil
+}
+
 func (this PageRequest) Offset() int {
 	return (this.PageV() - 1) * this.PageSizeV()
 }
@@ -82,6 +211,26 @@ type PageResponse struct {
 	Page     int         `json:"page"`
 	PageSize int         `json:"pageSize"`
 	Results  interface{} `json:"results"`
+	// CountMode the count mode produced Total , Total is 0 in CountNone mode
+	CountMode string `json:"countMode,omitempty"`
+	// HasMore whether there is a next page , only set in CountNone mode
+	HasMore *bool `json:"hasMore,omitempty"`
+	// MaxPageSize the page size limit , 0 means unlimited
+	MaxPageSize int `json:"maxPageSize,omitempty"`
+	// MaxOffset the deep paging limit of (page-1)*pageSize , 0 means unlimited
+	MaxOffset int `json:"maxOffset,omitempty"`
+}
+
+// PageResponseOf typed PageResponse
+type PageResponseOf[T any] struct {
+	Total       int64  `json:"total"`
+	Page        int    `json:"page"`
+	PageSize    int    `json:"pageSize"`
+	Results     []T    `json:"results"`
+	CountMode   string `json:"countMode,omitempty"`
+	HasMore     *bool  `json:"hasMore,omitempty"`
+	MaxPageSize int    `json:"maxPageSize,omitempty"`
+	MaxOffset   int    `json:"maxOffset,omitempty"`
 }
 
 type (
@@ -91,18 +240,37 @@ type (
 	pageCtx struct {
 		tx              *gorm.DB
 		beginEndCol     string
-		keyFuzzyCols    []string
+		keyFuzzyCols    []keyMatchCol
 		orderColsMap    map[string]string
+		defaultOrder    []orderItem
+		filterCols      map[string]FilterableCol
+		fullText        *FullTextSearch
+		countMode       string
+		countCacheTTL   time.Duration
+		limits          PageLimits
+		selectableCols  map[string]string
+		selects         []string
+		preloads        []queryItem
+		joins           []queryItem
+		groupCols       map[string]string
+		aggregateCols   map[string]string
+		schema          *schema.Schema
 		resultConverter resultConverterFunc
 	}
 	resultConverterFunc func(src interface{}) interface{}
 	pageOptionFunc      func(ctx *pageCtx)
+	// queryItem a query with args , e.g. a preload or a join
+	queryItem struct {
+		query string
+		args  []interface{}
+	}
 )
 
 func (f pageOptionFunc) apply(ctx *pageCtx) {
 	f(ctx)
 }
 
+// WithBeginEndCol the time column PageRequest.Begin/End apply to , default the CreatedAt field of the model
 func WithBeginEndCol(col string) PageOption {
 	return pageOptionFunc(func(ctx *pageCtx) {
 		ctx.beginEndCol = col
@@ -111,13 +279,15 @@ func WithBeginEndCol(col string) PageOption {
 
 func WithKeyFuzzyCol(col string) PageOption {
 	return pageOptionFunc(func(ctx *pageCtx) {
-		ctx.keyFuzzyCols = append(ctx.keyFuzzyCols, col)
+		ctx.keyFuzzyCols = append(ctx.keyFuzzyCols, keyMatchCol{col: col, mode: MatchContains})
 	})
 }
 
 func WithKeyFuzzyCols(col ...string) PageOption {
 	return pageOptionFunc(func(ctx *pageCtx) {
-		ctx.keyFuzzyCols = append(ctx.keyFuzzyCols, col...)
+		for _, it := range col {
+			ctx.keyFuzzyCols = append(ctx.keyFuzzyCols, keyMatchCol{col: it, mode: MatchContains})
+		}
 	})
 }
 
@@ -137,12 +307,55 @@ func WithOrderCols(all map[string]string) PageOption {
 	})
 }
 
+// WithSelectableField allow param in PageRequest.Fields , selecting column col (default same as param)
+func WithSelectableField(param string, col ...string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		if len(col) > 0 {
+			ctx.selectableCols[param] = col[0]
+			return
+		}
+		ctx.selectableCols[param] = param
+	})
+}
+
+// WithSelectableFields allow all params in PageRequest.Fields , param -> column
+func WithSelectableFields(all map[string]string) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.selectableCols = all
+	})
+}
+
 func WithWhere(query interface{}, args ...interface{}) PageOption {
 	return pageOptionFunc(func(ctx *pageCtx) {
 		ctx.tx = ctx.tx.Where(query, args...)
 	})
 }
 
+// WithPreload preload the association assoc of the rows with optional conditions ,
+// only the rows query is affected
+func WithPreload(assoc string, conds ...interface{}) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.preloads = append(ctx.preloads, queryItem{query: assoc, args: conds})
+	})
+}
+
+// WithJoin join the association (or a raw join clause) , so conditions can use its columns.
+// a raw join may repeat a row , rows are then counted by COUNT(DISTINCT pk) and read by the distinct
+// primary keys matched , so the order of a raw join query can only use the columns of the model
+func WithJoin(assoc string, args ...interface{}) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.joins = append(ctx.joins, queryItem{query: assoc, args: args})
+	})
+}
+
+// WithDefaultOrder order by col when PageRequest.OrderBy is empty , calls are accumulated.
+// default is the CreatedAt field DESC , or the primary key DESC when the model has no CreatedAt
+func WithDefaultOrder(col string, desc bool) PageOption {
+	return pageOptionFunc(func(ctx *pageCtx) {
+		ctx.defaultOrder = append(ctx.defaultOrder, orderItem{col: col, desc: desc})
+	})
+}
+
 func WithOrder(order interface{}) PageOption {
 	return pageOptionFunc(func(ctx *pageCtx) {
 		ctx.tx = ctx.tx.Order(order)
@@ -155,13 +368,17 @@ func WithResultConverter(fn resultConverterFunc) PageOption {
 	})
 }
 
-func PageQuery[T schema.Tabler](tx *gorm.DB, page PageRequest, m T, opts ...PageOption) (*PageResponse, error) {
+func newPageCtx(tx *gorm.DB, opts ...PageOption) *pageCtx {
 	assert.Must(tx != nil, "tx must not be nil").Panic()
 	ctx := &pageCtx{
 		tx:              tx,
-		beginEndCol:     "created_at",
-		keyFuzzyCols:    make([]string, 0),
+		keyFuzzyCols:    make([]keyMatchCol, 0),
 		orderColsMap:    make(map[string]string),
+		filterCols:      make(map[string]FilterableCol),
+		selectableCols:  make(map[string]string),
+		groupCols:       make(map[string]string),
+		aggregateCols:   make(map[string]string),
+		limits:          GetPageLimits(),
 		resultConverter: nil,
 	}
 	for _, opt := range opts {
@@ -169,77 +386,269 @@ func PageQuery[T schema.Tabler](tx *gorm.DB, page PageRequest, m T, opts ...Page
 			opt.apply(ctx)
 		}
 	}
-	if page.BeginV() > 0 {
-		ctx.tx = ctx.tx.Where(fmt.Sprintf("%s >= ?", ctx.beginEndCol), time.Unix(page.BeginV(), 0))
+	return ctx
+}
+
+// orderCols the orderBy whitelist , including the full text rank param
+func (ctx *pageCtx) orderCols() map[string]string {
+	if ctx.fullText == nil {
+		return ctx.orderColsMap
 	}
-	if page.EndV() > 0 {
-		ctx.tx = ctx.tx.Where(fmt.Sprintf("%s <= ?", ctx.beginEndCol), time.Unix(page.EndV(), 0))
+	ret := make(map[string]string, len(ctx.orderColsMap)+1)
+	for k, v := range ctx.orderColsMap {
+		ret[k] = v
 	}
-	if len(page.OrderBy) > 0 {
-		//check order
-		colLimit := ctx.orderColsMap
-		orderCol, ok := colLimit[page.OrderBy]
-		if !ok {
-			return nil, errors.Errorf("orderBy '%s' not allowed , must be one of [%s]", page.OrderBy,
-				mkArrayString(colLimit))
+	ret[ctx.fullText.rankParam()] = ""
+	return ret
+}
+
+// parseOrders parse orderBy with the whitelist , the full text rank is resolved with key ,
+// and dropped when key is empty
+func (ctx *pageCtx) parseOrders(orderBy string, reserve bool, key string, s *schema.Schema) ([]orderItem, error) {
+	orders, err := parseOrderBy(orderBy, reserve, ctx.orderCols())
+	if err != nil || ctx.fullText == nil {
+		return orders, err
+	}
+	ret := orders[:0]
+	for _, item := range orders {
+		if item.param == ctx.fullText.rankParam() {
+			if len(key) == 0 {
+				continue
+			}
+			item.col, item.vars = ctx.fullTextRank(key, s)
 		}
-		ctx.tx = ctx.tx.Order(fmt.Sprintf("%s %s", orderCol, page.OrderV()))
-	} else {
-		//default order by begin end filter column desc
-		ctx.tx = ctx.tx.Order(fmt.Sprintf("%s DESC", ctx.beginEndCol))
-	}
-	if len(page.Key) > 0 {
-		key := page.Key
-		orCols := make([]string, 0)
-		args := make([]interface{}, 0)
-		for _, col := range ctx.keyFuzzyCols {
-			orCols = append(orCols, fmt.Sprintf("%s LIKE ? ", col))
-			args = append(args, "%"+key+"%")
-		}
-		if len(orCols) > 0 {
-			orQueryStr := fmt.Sprintf("(%s)", strings.Join(orCols, " OR "))
-			ctx.tx = ctx.tx.Where(orQueryStr, args...)
-		}
-	}
-	//mType := reflect.TypeOf(m)
-	//if mType.Kind() != reflect.Ptr || mType.Elem().Kind() != reflect.Struct {
-	//	return nil, errors.Errorf("m must be a pointer to a struct")
-	//}
-	//dbResults := reflect.Zero(reflect.SliceOf(mType)).Interface()
-	dbResults := make([]T, 0)
-	//dbResults := make([]interface{}, 0)
-	total := int64(0)
-	err := ctx.tx.Offset(-1).
-		Model(m).
-		Limit(-1).
-		Count(&total).
-		Offset(page.Offset()).
-		Limit(page.Limit()).
-		Find(&dbResults).Error
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
+// applyOrders add orders to tx , orders with vars are built into one expression
+func (ctx *pageCtx) applyOrders(orders []orderItem) {
+	exprs := make([]string, 0, len(orders))
+	vars := make([]interface{}, 0)
+	for _, item := range orders {
+		exprs = append(exprs, item.String())
+		vars = append(vars, item.vars...)
+	}
+	if len(vars) > 0 {
+		ctx.tx = ctx.tx.Order(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(exprs, ","), Vars: vars}})
+		return
+	}
+	for _, expr := range exprs {
+		ctx.tx = ctx.tx.Order(expr)
+	}
+}
+
+// applyKey match key on all keyFuzzyCols , wildcards in key are escaped.
+// full text search is used instead when configured
+func (ctx *pageCtx) applyKey(key string, s *schema.Schema) error {
+	if len(key) == 0 {
+		return nil
+	}
+	if ctx.fullText != nil {
+		query, args, err := ctx.fullTextCond(key, s)
+		if err != nil {
+			return err
+		}
+		ctx.tx = ctx.tx.Where(query, args...)
+		return nil
+	}
+	orCols := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, col := range ctx.keyFuzzyCols {
+		query, arg := likeCond(ctx.tx, col.col, key, col.mode, col.ignoreCase)
+		orCols = append(orCols, query)
+		args = append(args, arg)
+	}
+	if len(orCols) > 0 {
+		orQueryStr := fmt.Sprintf("(%s)", strings.Join(orCols, " OR "))
+		ctx.tx = ctx.tx.Where(orQueryStr, args...)
+	}
+	return nil
+}
+
+// convertResults apply the result converter if present
+func convertResults[T any](ctx *pageCtx, dbResults []T) interface{} {
+	if ctx.resultConverter == nil {
+		return dbResults
+	}
+	resultsCvt := make([]interface{}, 0)
+	for i := range len(dbResults) {
+		resultsCvt = append(resultsCvt, ctx.resultConverter(dbResults[i]))
+	}
+	return resultsCvt
+}
+
+func PageQuery[T schema.Tabler](tx *gorm.DB, page PageRequest, m T, opts ...PageOption) (*PageResponse, error) {
+	ctx := newPageCtx(tx, opts...)
+	page, err := ctx.limitPage(page)
+	if err != nil {
+		return nil, err
+	}
+	dbResults, meta, err := pageQuery(ctx, page, m)
 	if err != nil {
 		return nil, err
 	}
-	//dbResultsVal := reflect.ValueOf(dbResults)
 	resp := &PageResponse{
-		Total:    total,
-		Page:     page.PageV(),
-		PageSize: page.PageSizeV(),
-		Results:  nil,
-	}
-	if ctx.resultConverter != nil {
-		resultsCvt := make([]interface{}, 0)
-		for i := range len(dbResults) {
-			//for i := range dbResultsVal.Len() {
-			//resultsCvt = append(resultsCvt, ctx.resultConverter(dbResultsVal.Index(i).Interface()))
-			resultsCvt = append(resultsCvt, ctx.resultConverter(dbResults[i]))
-		}
-		resp.Results = resultsCvt
-	} else {
-		resp.Results = dbResults
+		Total:       meta.total,
+		Page:        page.PageV(),
+		PageSize:    page.PageSizeV(),
+		Results:     nil,
+		CountMode:   meta.countMode,
+		HasMore:     meta.hasMore,
+		MaxPageSize: ctx.limits.MaxPageSize,
+		MaxOffset:   ctx.limits.MaxOffset,
 	}
+	resp.Results = convertResults(ctx, dbResults)
 	return resp, nil
 }
 
+// PageQueryAs same as PageQuery , but results are converted to R by converter with type safety ,
+// an error returned by converter aborts the query. WithResultConverter is ignored
+func PageQueryAs[M schema.Tabler, R any](tx *gorm.DB, page PageRequest, m M, converter func(M) (R, error),
+	opts ...PageOption) (*PageResponseOf[R], error) {
+	assert.Must(converter != nil, "converter must not be nil").Panic()
+	ctx := newPageCtx(tx, opts...)
+	page, err := ctx.limitPage(page)
+	if err != nil {
+		return nil, err
+	}
+	dbResults, meta, err := pageQuery(ctx, page, m)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]R, 0, len(dbResults))
+	for i := range dbResults {
+		r, err := converter(dbResults[i])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return &PageResponseOf[R]{
+		Total:       meta.total,
+		Page:        page.PageV(),
+		PageSize:    page.PageSizeV(),
+		Results:     results,
+		CountMode:   meta.countMode,
+		HasMore:     meta.hasMore,
+		MaxPageSize: ctx.limits.MaxPageSize,
+		MaxOffset:   ctx.limits.MaxOffset,
+	}, nil
+}
+
+func pageQuery[T schema.Tabler](ctx *pageCtx, page PageRequest, m T) ([]T, pageMeta, error) {
+	if err := ctx.prepare(page, m); err != nil {
+		return nil, pageMeta{}, err
+	}
+	return findPage[T](ctx, page, m)
+}
+
+// prepare apply the conditions and orders of page to ctx.tx , paging is not applied
+func (ctx *pageCtx) prepare(page PageRequest, m interface{}) error {
+	if err := ctx.applyConds(page, m); err != nil {
+		return err
+	}
+	//check order
+	orders, err := ctx.parseOrders(page.OrderBy, page.Reserve, page.Key, ctx.schema)
+	if err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		orders = ctx.defaultOrders(ctx.schema)
+	}
+	ctx.applyOrders(withPkTiebreaker(orders, ctx.schema))
+	return ctx.parseFields(page.Fields)
+}
+
+// applyConds apply the time window , joins , key and filter of page to ctx.tx
+func (ctx *pageCtx) applyConds(page PageRequest, m interface{}) error {
+	mSchema, err := modelSchema(ctx.tx, m)
+	if err != nil {
+		return err
+	}
+	ctx.schema = mSchema
+	if page.BeginV() > 0 || page.EndV() > 0 {
+		col, err := ctx.timeCol(mSchema)
+		if err != nil {
+			return err
+		}
+		if page.BeginV() > 0 {
+			ctx.tx = ctx.tx.Where(fmt.Sprintf("%s >= ?", col), time.Unix(page.BeginV(), 0))
+		}
+		if page.EndV() > 0 {
+			ctx.tx = ctx.tx.Where(fmt.Sprintf("%s <= ?", col), time.Unix(page.EndV(), 0))
+		}
+	}
+	ctx.applyJoins()
+	if err = ctx.applyKey(page.Key, mSchema); err != nil {
+		return err
+	}
+	return ctx.applyFilter(page.Filter, mSchema)
+}
+
+// parseFields check fields against the selectable whitelist
+func (ctx *pageCtx) parseFields(fields string) error {
+	seen := make(map[string]bool)
+	for _, param := range strings.Split(fields, ",") {
+		param = strings.TrimSpace(param)
+		if len(param) == 0 {
+			continue
+		}
+		col, ok := ctx.selectableCols[param]
+		if !ok {
+			return errors.Errorf("field '%s' not allowed , must be one of [%s]", param,
+				mkArrayString(ctx.selectableCols))
+		}
+		if !seen[col] {
+			seen[col] = true
+			ctx.selects = append(ctx.selects, col)
+		}
+	}
+	return nil
+}
+
+func (ctx *pageCtx) applyJoins() {
+	for _, join := range ctx.joins {
+		ctx.tx = ctx.tx.Joins(join.query, join.args...)
+	}
+}
+
+// dataQuery apply what only affects the rows query (not the count) to tx
+func (ctx *pageCtx) dataQuery(tx *gorm.DB) *gorm.DB {
+	// a raw one-to-many join repeats a row per match , the rows are read by the distinct primary
+	// keys matched through the joins instead , which works on every dialect
+	if pk := ctx.joinedPkCol(); pk != "" {
+		matched := tx.Session(&gorm.Session{}).Offset(-1).Limit(-1).Select(pk)
+		delete(matched.Statement.Clauses, "ORDER BY")
+		tx = tx.Session(&gorm.Session{}).Scopes()
+		delete(tx.Statement.Clauses, "WHERE")
+		tx.Statement.Joins = nil
+		tx = tx.Where(fmt.Sprintf("%s IN (?)", pk), matched)
+	}
+	if len(ctx.selects) > 0 {
+		tx = tx.Select(ctx.selects)
+	}
+	for _, preload := range ctx.preloads {
+		tx = tx.Preload(preload.query, preload.args...)
+	}
+	return tx
+}
+
+// joinedPkCol the qualified primary key column when raw joins (not an association of the model) are
+// present , rows are de-duplicated by it. an association join is a belongs to / has one join
+func (ctx *pageCtx) joinedPkCol() string {
+	if ctx.schema == nil || ctx.schema.PrioritizedPrimaryField == nil {
+		return ""
+	}
+	for _, join := range ctx.joins {
+		if _, ok := ctx.schema.Relationships.Relations[strings.Split(join.query, ".")[0]]; !ok {
+			return fmt.Sprintf("%s.%s", ctx.schema.Table, ctx.schema.PrioritizedPrimaryField.DBName)
+		}
+	}
+	return ""
+}
+
 func mkArrayString(names map[string]string) string {
 	dbStrElements := make([]string, 0, len(names))
 	for k := range names {
@@ -1,9 +1,18 @@
 package db
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -66,3 +75,516 @@ func TestPageQuery(t *testing.T) {
 	}
 	t.Log(res)
 }
+
+func TestParseOrderBy(t *testing.T) {
+	colLimit := map[string]string{"age": "age", "createdAt": "created_at"}
+	orders, err := parseOrderBy("age, -createdAt", false, colLimit)
+	if err != nil {
+		t.Fatalf("parse err %v", err)
+	}
+	if len(orders) != 2 || orders[0].String() != "age ASC" || orders[1].String() != "created_at DESC" {
+		t.Fatalf("unexpected orders %v", orders)
+	}
+	orders, _ = parseOrderBy("+age", true, colLimit)
+	if len(orders) != 1 || orders[0].String() != "age ASC" {
+		t.Fatalf("unexpected orders %v", orders)
+	}
+	if _, err = parseOrderBy("age,name", false, colLimit); err == nil {
+		t.Fatalf("expect not allowed error")
+	}
+	if _, err = parseOrderBy("age,-age", false, colLimit); err == nil {
+		t.Fatalf("expect duplicate error")
+	}
+}
+
+func TestPageQueryFilter(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	for i := 0; i < 20; i++ {
+		orm.Create(&user{
+			Name: fmt.Sprintf("User%d", i),
+			Age:  i,
+			Sex:  []string{"m", "f", "x"}[i%3],
+		})
+	}
+	values, _ := url.ParseQuery("filter[age][gte]=5&filter[age][lt]=15&filter[sex][in]=m,f&filter[name][ilike]=user1")
+	pageReq := PageRequest{Filter: ParseFilter(values)}
+	opts := []PageOption{
+		WithFilterableCol("age", "age", FilterGte, FilterLt),
+		WithFilterableCol("sex", "sex"),
+		WithFilterableCol("name", "name", FilterILike),
+	}
+	res, err := PageQuery[*user](orm, pageReq, new(user), opts...)
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	// age 10..14 , excluding sex x (age 11 and 14)
+	if res.Total != 3 {
+		t.Fatalf("expect 3 users , got %d", res.Total)
+	}
+	pageReq.Filter = Filter{"age": {FilterEq: "1"}}
+	if _, err = PageQuery[*user](orm, pageReq, new(user), opts...); err == nil {
+		t.Fatalf("expect operator not allowed error")
+	}
+	pageReq.Filter = Filter{"age": {FilterGte: "abc"}}
+	if _, err = PageQuery[*user](orm, pageReq, new(user), opts...); err == nil {
+		t.Fatalf("expect invalid value error")
+	}
+	pageReq.Filter = Filter{"id": {FilterEq: "1"}}
+	if _, err = PageQuery[*user](orm, pageReq, new(user), opts...); err == nil {
+		t.Fatalf("expect filter not allowed error")
+	}
+	// projection
+	pageReq = PageRequest{Fields: "name"}
+	opts = []PageOption{WithSelectableField("id"), WithSelectableField("name")}
+	res, err = PageQuery[*user](orm, pageReq, new(user), opts...)
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	u := res.Results.([]*user)[0]
+	if res.Total != 20 || u.Name == "" || u.ID != "" || u.Age != 0 {
+		t.Fatalf("unexpected projection %+v", u)
+	}
+	pageReq.Fields = "name,age"
+	if _, err = PageQuery[*user](orm, pageReq, new(user), opts...); err == nil {
+		t.Fatalf("expect field not allowed error")
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	cases := map[string]string{
+		likePattern("50%_off", MatchContains): `%50\%\_off%`,
+		likePattern(`a\b`, MatchPrefix):       `a\\b%`,
+		likePattern("jo", MatchSuffix):        "%jo",
+	}
+	for got, expect := range cases {
+		if got != expect {
+			t.Fatalf("expect %s , got %s", expect, got)
+		}
+	}
+}
+
+func TestPageQueryEscapedKey(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	for _, name := range []string{"50% OFF", "500 off", "a_b", "axb"} {
+		orm.Create(&user{Name: name})
+	}
+	for _, c := range []struct {
+		key  string
+		opts []PageOption
+		want string
+	}{
+		{"50%", []PageOption{WithKeyFuzzyCols("name")}, "50% OFF"},
+		{"50% off", []PageOption{WithKeyFuzzyColsIgnoreCase("name")}, "50% OFF"},
+		{"a_", []PageOption{WithKeyMatchCol("name", MatchPrefix, false)}, "a_b"},
+	} {
+		res, err := PageQuery[*user](orm, PageRequest{Key: c.key}, new(user), c.opts...)
+		if err != nil {
+			t.Fatalf("query err %v", err)
+		}
+		if users := res.Results.([]*user); len(users) != 1 || users[0].Name != c.want {
+			t.Fatalf("key %s : expect only %s , got %d users", c.key, c.want, len(users))
+		}
+	}
+	res, err := PageQuery[*user](orm, PageRequest{Filter: Filter{"name": {FilterILike: "_B"}}}, new(user),
+		WithFilterableCol("name", "name"))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if res.Total != 1 {
+		t.Fatalf("expect only a_b by the ilike filter , got %d", res.Total)
+	}
+}
+
+func TestPageQueryCountMode(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	for i := 0; i < 15; i++ {
+		orm.Create(&user{Name: fmt.Sprintf("user%d", i), Age: i})
+	}
+	pageSize := 10
+	pageReq := PageRequest{PageSize: &pageSize}
+	res, err := PageQuery[*user](orm, pageReq, new(user), WithCountMode(CountNone))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if res.CountMode != CountNone || res.HasMore == nil || !*res.HasMore || len(res.Results.([]*user)) != 10 {
+		t.Fatalf("unexpected none mode response %+v", res)
+	}
+	opts := []PageOption{WithCountMode(CountCached), WithWhere("age >= ?", 5)}
+	res, err = PageQuery[*user](orm, pageReq, new(user), opts...)
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if res.CountMode != CountCached || res.Total != 10 {
+		t.Fatalf("unexpected cached mode response %+v", res)
+	}
+	orm.Create(&user{Name: "user15", Age: 15})
+	res, _ = PageQuery[*user](orm, pageReq, new(user), opts...)
+	if res.Total != 10 {
+		t.Fatalf("expect cached total 10 , got %d", res.Total)
+	}
+	// no estimate on sqlite , falls back to exact
+	res, _ = PageQuery[*user](orm, pageReq, new(user), WithCountMode(CountEstimate))
+	if res.CountMode != CountExact || res.Total != 16 {
+		t.Fatalf("unexpected estimate mode response %+v", res)
+	}
+	// the same query on another datasource is cached apart
+	shard := newTestORM(t, new(user))
+	shard.Create(&user{Name: "user5", Age: 5})
+	res, _ = PageQuery[*user](shard, pageReq, new(user), opts...)
+	if res.Total != 1 {
+		t.Fatalf("expect the total of the other datasource , got %d", res.Total)
+	}
+	// pg_class.reltuples only applies to the whole table
+	s, _ := modelSchema(orm, new(user))
+	for _, c := range []struct {
+		tx         *gorm.DB
+		unfiltered bool
+	}{
+		{orm, false}, // soft delete
+		{orm.Unscoped(), true},
+		{orm.Unscoped().Where("age > ?", 1), false},
+		{orm.Unscoped().Joins("JOIN t_pets ON t_pets.owner_id = t_users.id"), false},
+	} {
+		if newPageCtx(c.tx).unfiltered(s) != c.unfiltered {
+			t.Fatalf("expect unfiltered %v of %+v", c.unfiltered, c.tx.Statement.Clauses)
+		}
+	}
+	// the planner estimates the rows query , not the COUNT(*) aggregate nor the page
+	rows := newPageCtx(orm.Where("age > ?", 1).Offset(10).Limit(10)).rowsStatement(new(user)).SQL.String()
+	if strings.Contains(strings.ToLower(rows), "count(") || strings.Contains(rows, "LIMIT") ||
+		!strings.Contains(rows, "age > ?") {
+		t.Fatalf("unexpected estimated rows sql %s", rows)
+	}
+}
+
+func TestExportQuery(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	for i := 0; i < 5; i++ {
+		orm.Create(&user{Name: fmt.Sprintf("user,%d", i), Age: i})
+	}
+	buf := new(bytes.Buffer)
+	pageReq := PageRequest{OrderBy: "age"}
+	n, err := ExportQuery[*user](orm, pageReq, new(user), buf, ExportConfig{
+		Format:  ExportCSV,
+		Fields:  []string{"Name", "Age"},
+		Headers: map[string]string{"Name": "name"},
+	}, WithOrderCol("age"), WithWhere("age >= ?", 2))
+	if err != nil {
+		t.Fatalf("export err %v", err)
+	}
+	expect := "name,Age\n\"user,2\",2\n\"user,3\",3\n\"user,4\",4\n"
+	if n != 3 || buf.String() != expect {
+		t.Fatalf("unexpected export %d rows :\n%s", n, buf.String())
+	}
+	buf.Reset()
+	if _, err = ExportQuery[*user](orm, pageReq, new(user), buf, ExportConfig{Format: ExportXLSX},
+		WithOrderCol("age")); err != nil {
+		t.Fatalf("export err %v", err)
+	}
+	if _, err = zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatalf("invalid xlsx %v", err)
+	}
+	if _, err = ExportQuery[*user](orm, pageReq, new(user), buf, ExportConfig{Format: ExportCSV, Fields: []string{"x"}},
+		WithOrderCol("age")); err == nil {
+		t.Fatalf("expect unknown field error")
+	}
+	// formulas are not run by spreadsheets , fields of the request are selected
+	orm.Create(&user{Name: "=HYPERLINK(\"http://x\")", Age: -1, Sex: "m"})
+	buf.Reset()
+	pageReq = PageRequest{Fields: "Name,Age"}
+	if _, err = ExportQuery[*user](orm, pageReq, new(user), buf, ExportConfig{Format: ExportCSV,
+		Fields: []string{"Name", "Age", "Sex"}}, WithWhere("age < ?", 0),
+		WithSelectableFields(map[string]string{"Name": "name", "Age": "age"})); err != nil {
+		t.Fatalf("export err %v", err)
+	}
+	if expect = "Name,Age,Sex\n\"'=HYPERLINK(\"\"http://x\"\")\",-1,\n"; buf.String() != expect {
+		t.Fatalf("unexpected export :\n%s", buf.String())
+	}
+	buf.Reset()
+	if _, err = ExportQuery[*user](orm, PageRequest{}, new(user), buf, ExportConfig{Format: ExportXLSX,
+		Fields: []string{"Name"}}, WithWhere("age < ?", 0)); err != nil {
+		t.Fatalf("export err %v", err)
+	}
+	zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	sheet, _ := zr.Open("xl/worksheets/sheet1.xml")
+	content, _ := io.ReadAll(sheet)
+	if !strings.Contains(string(content), `<c t="inlineStr"><is><t xml:space="preserve">=HYPERLINK(`) {
+		t.Fatalf("expect the formula written as a string cell :\n%s", content)
+	}
+}
+
+type pet struct {
+	ID      uint   `gorm:"column:id;primaryKey"`
+	OwnerID string `gorm:"column:owner_id"`
+	Kind    string `gorm:"column:kind"`
+}
+
+func (*pet) TableName() string {
+	return "t_pets"
+}
+
+type owner struct {
+	UuidPriWithCreateDelAtBase
+	Name string `gorm:"column:name"`
+	Pets []pet  `gorm:"foreignKey:OwnerID"`
+}
+
+func (*owner) TableName() string {
+	return "t_users"
+}
+
+func TestPageQueryJoin(t *testing.T) {
+	orm := newTestORM(t, new(user), new(pet))
+	for i := 0; i < 3; i++ {
+		u := &user{Name: fmt.Sprintf("user%d", i)}
+		orm.Create(u)
+		for j := 0; j <= i; j++ {
+			orm.Create(&pet{OwnerID: u.ID, Kind: "cat"})
+		}
+	}
+	res, err := PageQueryAs(orm, PageRequest{}, new(owner), func(o *owner) (*owner, error) {
+		return o, nil
+	},
+		WithJoin("JOIN t_pets ON t_pets.owner_id = t_users.id"),
+		WithWhere("t_pets.kind = ?", "cat"),
+		WithPreload("Pets"),
+		WithSelectableField("id", "t_users.id"))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if res.Total != 3 || len(res.Results) != 3 {
+		t.Fatalf("expect 3 owners , got %d of %d", len(res.Results), res.Total)
+	}
+	for _, o := range res.Results {
+		if len(o.Pets) == 0 {
+			t.Fatalf("pets of %s not preloaded", o.Name)
+		}
+	}
+}
+
+type company struct {
+	ID   uint   `gorm:"column:id;primaryKey"`
+	Name string `gorm:"column:name"`
+}
+
+func (*company) TableName() string {
+	return "t_companies"
+}
+
+type emp struct {
+	Int64PrimaryKey
+	Name      string  `gorm:"column:name"`
+	CompanyID uint    `gorm:"column:company_id"`
+	Company   company `gorm:"foreignKey:CompanyID"`
+}
+
+func (*emp) TableName() string {
+	return "t_emps"
+}
+
+func TestPageQueryAssociationJoin(t *testing.T) {
+	orm := newTestORM(t, new(company), new(emp))
+	for i := 1; i <= 2; i++ {
+		orm.Create(&company{ID: uint(i), Name: fmt.Sprintf("c%d", i)})
+	}
+	for i := 0; i < 4; i++ {
+		orm.Create(&emp{Name: fmt.Sprintf("e%d", i), CompanyID: uint(i%2 + 1)})
+	}
+	// the selected columns of the association must not be grouped (postgres rejects a GROUP BY pk)
+	sqls := make([]string, 0)
+	_ = orm.Callback().Query().After("gorm:query").Register("test:sql", func(db *gorm.DB) {
+		sqls = append(sqls, db.Statement.SQL.String())
+	})
+	res, err := PageQueryAs(orm, PageRequest{OrderBy: "company"}, new(emp), func(e *emp) (*emp, error) {
+		return e, nil
+	},
+		WithJoin("Company"),
+		WithWhere("Company.name <> ?", "c3"),
+		WithOrderCol("company", "Company.name"))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if res.Total != 4 || len(res.Results) != 4 || res.Results[0].Company.Name != "c1" || res.Results[3].Company.Name != "c2" {
+		t.Fatalf("unexpected joined emps %+v", res)
+	}
+	for _, sql := range sqls {
+		if strings.Contains(sql, "GROUP BY") {
+			t.Fatalf("unexpected grouped query %s", sql)
+		}
+	}
+	// the default cursor key is qualified , t_companies has an id column too
+	limit := 3
+	cursor, err := CursorQuery[*emp](orm, CursorRequest{Limit: &limit}, new(emp), WithJoin("Company"))
+	if err != nil || len(cursor.Results.([]*emp)) != 3 {
+		t.Fatalf("unexpected cursor page %+v , %v", cursor, err)
+	}
+	conn, err := ConnectionQuery[*emp](orm, ConnectionArgs{First: &limit}, new(emp), WithJoin("Company"))
+	if err != nil || len(conn.Edges) != 3 {
+		t.Fatalf("unexpected connection %+v , %v", conn, err)
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	values, _ := url.ParseQuery("page=2&pageSize=20&begin=10&end=20&key=tom&orderBy=-age&reserve=true&filter[age][gte]=18")
+	page, err := ParsePageRequest(values)
+	if err != nil {
+		t.Fatalf("parse err %v", err)
+	}
+	if page.PageV() != 2 || page.PageSizeV() != 20 || page.BeginV() != 10 || page.EndV() != 20 ||
+		page.Key != "tom" || page.OrderBy != "-age" || !page.Reserve || page.Filter["age"]["gte"] != "18" {
+		t.Fatalf("unexpected page request %+v", page)
+	}
+	values, _ = url.ParseQuery("page=x&pageSize=100000&begin=10&end=5")
+	_, err = ParsePageRequest(values)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expect validation error , got %v", err)
+	}
+	fields := make([]string, 0)
+	for _, it := range verr.Fields {
+		fields = append(fields, it.Field)
+	}
+	if strings.Join(fields, ",") != "page,pageSize,end" {
+		t.Fatalf("unexpected invalid fields %v", fields)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"page":3,"pageSize":5,"orderBy":"age"}`))
+	r.Header.Set("Content-Type", "application/json")
+	page, err = BindPageRequest(r)
+	if err != nil || page.PageV() != 3 || page.PageSizeV() != 5 || page.OrderBy != "age" {
+		t.Fatalf("unexpected bind result %+v , %v", page, err)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"page":"3"}`))
+	r.Header.Set("Content-Type", "application/json")
+	if _, err = BindPageRequest(r); !errors.As(err, &verr) || verr.Fields[0].Field != "page" {
+		t.Fatalf("expect page validation error , got %v", err)
+	}
+	// DefaultMaxPageSize while the queries are unlimited , else the limit of the queries ,
+	// nothing rejected when they clamp
+	if _, err = ParsePageRequest(url.Values{"pageSize": {strconv.Itoa(DefaultMaxPageSize + 1)}}); !errors.As(err, &verr) {
+		t.Fatalf("expect pageSize validation error , got %v", err)
+	}
+	defer SetPageLimits(GetPageLimits())
+	SetPageLimits(PageLimits{MaxPageSize: 50})
+	if _, err = ParsePageRequest(url.Values{"pageSize": {"60"}}); !errors.As(err, &verr) {
+		t.Fatalf("expect pageSize validation error , got %v", err)
+	}
+	SetPageLimits(PageLimits{MaxPageSize: 50, Clamp: true})
+	if _, err = ParsePageRequest(url.Values{"pageSize": {"60"}}); err != nil {
+		t.Fatalf("clamped pageSize should pass , got %v", err)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/users?page=4&key=jerry", nil)
+	if page, err = BindPageRequest(r); err != nil || page.PageV() != 4 || page.Key != "jerry" {
+		t.Fatalf("unexpected bind result %+v , %v", page, err)
+	}
+}
+
+func TestAggregateQuery(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	day := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		u := &user{Name: fmt.Sprintf("user%d", i), Age: i, Sex: []string{"f", "m"}[i%2]}
+		u.CreatedAt.CreatedAt = day.AddDate(0, 0, i/2)
+		orm.Create(u)
+	}
+	opts := []PageOption{WithGroupByCol("sex"), WithAggregateCol("age")}
+	req := AggregateRequest{GroupBy: "sex", Aggregates: "count,sum:age,max:age", Bucket: BucketDay}
+	res, err := AggregateQuery[*user](orm, req, new(user), opts...)
+	if err != nil {
+		t.Fatalf("aggregate err %v", err)
+	}
+	if res.Total != 6 || len(res.Results) != 6 {
+		t.Fatalf("unexpected aggregate response %+v", res)
+	}
+	first := res.Results[0]
+	if first["bucket"] != "2024-01-01 00:00:00" || first["sex"] != "f" ||
+		fmt.Sprint(first["count"]) != "1" || fmt.Sprint(first["max_age"]) != "0" {
+		t.Fatalf("unexpected first group %v", first)
+	}
+	pageSize := 1
+	req = AggregateRequest{PageRequest: PageRequest{PageSize: &pageSize, OrderBy: "-sum_age"},
+		GroupBy: "sex", Aggregates: "sum:age"}
+	res, err = AggregateQuery[*user](orm, req, new(user), opts...)
+	if err != nil {
+		t.Fatalf("aggregate err %v", err)
+	}
+	if res.Total != 2 || len(res.Results) != 1 || res.Results[0]["sex"] != "m" || fmt.Sprint(res.Results[0]["sum_age"]) != "9" {
+		t.Fatalf("unexpected aggregate response %+v", res)
+	}
+	if _, err = AggregateQuery[*user](orm, AggregateRequest{GroupBy: "name"}, new(user), opts...); err == nil {
+		t.Fatalf("expect groupBy not allowed error")
+	}
+	if _, err = AggregateQuery[*user](orm, AggregateRequest{Aggregates: "sum:name"}, new(user), opts...); err == nil {
+		t.Fatalf("expect aggregate not allowed error")
+	}
+}
+
+type tag struct {
+	Int64PrimaryKey
+	Name string `gorm:"column:name"`
+}
+
+func (*tag) TableName() string {
+	return "t_tags"
+}
+
+func TestPageQueryDefaultOrder(t *testing.T) {
+	orm := newTestORM(t, new(tag))
+	for _, name := range []string{"b", "c", "a"} {
+		orm.Create(&tag{Name: name})
+	}
+	// no created_at , ordered by the primary key DESC
+	res, err := PageQuery[*tag](orm, PageRequest{}, new(tag))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if tags := res.Results.([]*tag); len(tags) != 3 || tags[0].Name != "a" || tags[2].Name != "b" {
+		t.Fatalf("unexpected pk order %v", tags)
+	}
+	res, err = PageQuery[*tag](orm, PageRequest{}, new(tag), WithDefaultOrder("name", false))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if tags := res.Results.([]*tag); tags[0].Name != "a" || tags[1].Name != "b" {
+		t.Fatalf("unexpected default order %v", tags)
+	}
+	if _, err = PageQuery[*tag](orm, PageRequest{Begin: 1}, new(tag)); err == nil {
+		t.Fatalf("expect missing time column error")
+	}
+	if _, err = PageQuery[*tag](orm, PageRequest{Begin: 1}, new(tag), WithBeginEndCol("updated_at")); err == nil {
+		t.Fatalf("expect unknown time column error")
+	}
+}
+
+func TestPageLimits(t *testing.T) {
+	orm := newTestORM(t, new(user))
+	var err error
+	for i := 0; i < 12; i++ {
+		orm.Create(&user{Name: fmt.Sprintf("user%d", i), Age: i})
+	}
+	// unlimited by default
+	pageNo, pageSize := 1, 2000
+	req := PageRequest{Page: &pageNo, PageSize: &pageSize}
+	if res, err := PageQuery[*user](orm, req, new(user)); err != nil || len(res.Results.([]*user)) != 12 {
+		t.Fatalf("unexpected default limits response %+v , %v", res, err)
+	}
+	pageNo, pageSize = 50, 100
+	_, err = PageQuery[*user](orm, req, new(user), WithMaxPageSize(5))
+	var limitErr *PageLimitError
+	if !errors.As(err, &limitErr) || limitErr.Field != "pageSize" || limitErr.Max != 5 {
+		t.Fatalf("expect pageSize limit error , got %v", err)
+	}
+	res, err := PageQuery[*user](orm, req, new(user), WithMaxPageSize(5), WithMaxOffset(10), WithPageLimitClamp(true))
+	if err != nil {
+		t.Fatalf("query err %v", err)
+	}
+	if res.PageSize != 5 || res.Page != 3 || len(res.Results.([]*user)) != 2 || res.MaxPageSize != 5 || res.MaxOffset != 10 {
+		t.Fatalf("unexpected clamped response %+v", res)
+	}
+	defer SetPageLimits(GetPageLimits())
+	SetPageLimits(PageLimits{MaxOffset: 10})
+	pageSize = 5
+	_, err = PageQuery[*user](orm, req, new(user))
+	if !errors.As(err, &limitErr) || limitErr.Field != "offset" {
+		t.Fatalf("expect offset limit error , got %v", err)
+	}
+}
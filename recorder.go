@@ -0,0 +1,129 @@
+package db
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// QueryRecord a statement captured by the trace logger
+type QueryRecord struct {
+	Time       time.Time `json:"time"`
+	Datasource string    `json:"datasource"`
+	// SQL the statement with its literal values replaced by '?' , so no parameter is kept in memory
+	SQL        string  `json:"sql"`
+	DurationMs float64 `json:"durationMs"`
+	Rows       int64   `json:"rows"`
+	Error      string  `json:"error,omitempty"`
+	TraceId    string  `json:"traceId,omitempty"`
+	Caller     string  `json:"caller"`
+}
+
+// queryRecorder a bounded ring buffer of the latest statements
+type queryRecorder struct {
+	mu   sync.Mutex
+	buf  []QueryRecord
+	next int
+	full bool
+}
+
+func newQueryRecorder(size int) *queryRecorder {
+	if size <= 0 {
+		return nil
+	}
+	return &queryRecorder{buf: make([]QueryRecord, size)}
+}
+
+func (r *queryRecorder) add(record QueryRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf[r.next] = record
+	r.next++
+	if r.next == len(r.buf) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// list return the records matched filter , newest first
+func (r *queryRecorder) list(filter func(record *QueryRecord) bool) []QueryRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	size := r.next
+	if r.full {
+		size = len(r.buf)
+	}
+	ret := make([]QueryRecord, 0, size)
+	for i := 0; i < size; i++ {
+		idx := (r.next - 1 - i + len(r.buf)) % len(r.buf)
+		if filter == nil || filter(&r.buf[idx]) {
+			ret = append(ret, r.buf[idx])
+		}
+	}
+	return ret
+}
+
+// RecentQueries return the latest statements of datasource ds , newest first ,
+// empty ds means the default one. filter by traceId when it is not empty ,
+// and by duration when minDuration > 0
+func RecentQueries(ds string, traceId string, minDuration time.Duration) ([]QueryRecord, error) {
+	l, err := getTraceLogger(ds)
+	if err != nil {
+		return nil, err
+	}
+	if l.recorder == nil {
+		return nil, errors.Errorf("recent queries of db '%s' not enabled", ds)
+	}
+	minMs := float64(minDuration.Nanoseconds()) / 1e6
+	return l.recorder.list(func(record *QueryRecord) bool {
+		if traceId != "" && record.TraceId != traceId {
+			return false
+		}
+		return record.DurationMs >= minMs
+	}), nil
+}
+
+// RecentQueriesHandler serve the latest statements as json. the statements are redacted , but
+// table names , callers and timings still leak the internals , so mount it behind auth.
+// supported query parameters:
+//
+//	ds      : datasource name , empty means the default one
+//	traceId : only statements of the trace id
+//	minMs   : only statements took at least minMs milliseconds
+//	limit   : max records returned
+func RecentQueriesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var (
+			minMs int64
+			limit int
+			err   error
+		)
+		if v := q.Get("minMs"); v != "" {
+			if minMs, err = strconv.ParseInt(v, 10, 64); err != nil || minMs < 0 {
+				http.Error(w, "invalid minMs", http.StatusBadRequest)
+				return
+			}
+		}
+		if v := q.Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+		records, err := RecentQueries(q.Get("ds"), q.Get("traceId"), time.Duration(minMs)*time.Millisecond)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if limit > 0 && len(records) > limit {
+			records = records[:limit]
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(records)
+	})
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecentQueries(t *testing.T) {
+	l, _ := newObservedLogger(Config{name: "recorder_test", Type: DsTypeSqlLite, LogLevel: LogLevelSilent, RecentQueries: 3})
+	_ormLoggers.Store("recorder_test", l)
+	t.Cleanup(func() {
+		_ormLoggers.Delete("recorder_test")
+	})
+	for i := 0; i < 5; i++ {
+		ctx := context.WithValue(context.Background(), CtxTraceIdKey, fmt.Sprintf("t%d", i%2))
+		begin := time.Now().Add(-time.Duration(i*30) * time.Millisecond)
+		sql := fmt.Sprintf(`SELECT * FROM t_users WHERE email = "u%d@x.com" LIMIT %d`, i, i+1)
+		l.Trace(ctx, begin, sqlOf(sql), nil)
+	}
+	// the ring buffer keeps the latest 3 , newest first , without the values
+	records, err := RecentQueries("recorder_test", "", 0)
+	if err != nil {
+		t.Fatalf("recent queries err %v", err)
+	}
+	if len(records) != 3 || records[0].TraceId != "t0" || records[2].TraceId != "t0" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	if records[0].SQL != "SELECT * FROM t_users WHERE email = ? LIMIT ?" || records[0].Datasource != "recorder_test" {
+		t.Fatalf("unexpected record %+v", records[0])
+	}
+	get := func(query string) (*httptest.ResponseRecorder, []QueryRecord) {
+		w := httptest.NewRecorder()
+		RecentQueriesHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/queries?"+query, nil))
+		var ret []QueryRecord
+		if w.Code == http.StatusOK {
+			_ = json.Unmarshal(w.Body.Bytes(), &ret)
+		}
+		return w, ret
+	}
+	// records 2 (60ms) and 4 (120ms) are of t0
+	if _, ret := get("ds=recorder_test&traceId=t0"); len(ret) != 2 {
+		t.Fatalf("expect 2 records of t0 , got %d", len(ret))
+	}
+	if _, ret := get("ds=recorder_test&minMs=100"); len(ret) != 1 || ret[0].DurationMs < 100 {
+		t.Fatalf("expect 1 record over 100ms , got %+v", ret)
+	}
+	if _, ret := get("ds=recorder_test&limit=1"); len(ret) != 1 {
+		t.Fatalf("expect 1 record , got %d", len(ret))
+	}
+	if w, _ := get("ds=recorder_test&minMs=x"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expect bad request , got %d", w.Code)
+	}
+	if w, _ := get("ds=no_such_db"); w.Code != http.StatusNotFound {
+		t.Fatalf("expect not found , got %d", w.Code)
+	}
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// logSampler decide whether a normal (not error , not slow) sql statement should be logged ,
+// it keeps 1 in rate statements and at most limit statements per fingerprint per second
+type logSampler struct {
+	rate     uint64
+	limit    int64
+	interval time.Duration
+
+	counter     atomic.Uint64
+	dropped     atomic.Uint64
+	lastSummary atomic.Int64
+
+	mu     sync.Mutex
+	second int64
+	counts map[string]int64
+}
+
+func newLogSampler(config Config) *logSampler {
+	if config.SampleRate <= 1 && config.RateLimit <= 0 {
+		return nil
+	}
+	interval := time.Duration(config.SampleSummarySec) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ret := &logSampler{
+		rate:     uint64(max(config.SampleRate, 1)),
+		limit:    config.RateLimit,
+		interval: interval,
+		counts:   make(map[string]int64),
+	}
+	ret.lastSummary.Store(time.Now().UnixNano())
+	return ret
+}
+
+// allow report whether the statement should be logged , fingerprint (see sqlFingerprint) is only
+// evaluated when the statement passed the sampling
+func (s *logSampler) allow(fingerprint func() string) bool {
+	if s.counter.Add(1)%s.rate != 0 {
+		s.dropped.Add(1)
+		return false
+	}
+	if s.limit <= 0 {
+		return true
+	}
+	key := fingerprint()
+	now := time.Now().Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now != s.second {
+		s.second = now
+		clear(s.counts)
+	}
+	if s.counts[key] >= s.limit {
+		s.dropped.Add(1)
+		return false
+	}
+	s.counts[key]++
+	return true
+}
+
+// summary return the dropped count since last summary when the summary interval elapsed
+func (s *logSampler) summary() (dropped uint64, elapsed time.Duration, ok bool) {
+	now := time.Now().UnixNano()
+	last := s.lastSummary.Load()
+	elapsed = time.Duration(now - last)
+	if elapsed < s.interval || !s.lastSummary.CompareAndSwap(last, now) {
+		return 0, 0, false
+	}
+	dropped = s.dropped.Swap(0)
+	return dropped, elapsed, dropped > 0
+}
+
+var _inListRe = regexp.MustCompile(`\(\?(?:\s*,\s*\?)+\)`)
+
+// sqlFingerprint the statement with its literal values replaced by '?' and IN lists folded ,
+// so the executions of one statement with different arguments share a rate limit
+func sqlFingerprint(sql string, quote byte) string {
+	return _inListRe.ReplaceAllString(redactSQL(sql, quote), "(?)")
+}
+
+// redactSQL replace the string and number literals of an interpolated statement with '?' ,
+// quote is the quote the dialect interpolates string values with
+func redactSQL(sql string, quote byte) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == quote:
+			// skip to the closing quote , a doubled quote is an escaped one
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] != c {
+					continue
+				}
+				if j+1 < len(sql) && sql[j+1] == c {
+					j++
+					continue
+				}
+				break
+			}
+			b.WriteByte('?')
+			i = j
+		case isDigit(c) && (i == 0 || !isIdentByte(sql[i-1])):
+			for i+1 < len(sql) && (isDigit(sql[i+1]) || sql[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentByte(c byte) bool {
+	return isDigit(c) || c == '_' || c == '$' || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
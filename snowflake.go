@@ -0,0 +1,73 @@
+package db
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// snowflake id layout : 41 bits milliseconds since snowflakeEpoch , 10 bits node , 12 bits sequence
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+// snowflakeEpoch 2020-01-01 00:00:00 UTC in milliseconds
+const snowflakeEpoch int64 = 1577836800000
+
+type snowflake struct {
+	mu   sync.Mutex
+	node int64
+	// lastMs the millisecond of the last id , never goes back even if the clock does
+	lastMs int64
+	seq    int64
+}
+
+var _snowflake = &snowflake{}
+
+// SetSnowflakeNode set the node id (0-1023) of SnowflakePrimaryKey , read from the snowflakeNode
+// config of the default datasource. instances sharing a node may generate duplicated ids
+func SetSnowflakeNode(node int64) error {
+	if node < 0 || node > snowflakeMaxNode {
+		return errors.Errorf("snowflake node %d out of range [0,%d]", node, snowflakeMaxNode)
+	}
+	_snowflake.mu.Lock()
+	defer _snowflake.mu.Unlock()
+	_snowflake.node = node
+	return nil
+}
+
+// hostSnowflakeNode the node id derived from hostname , used when no snowflakeNode is configured
+func hostSnowflakeNode(hostname string) int64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(hostname))
+	return int64(h.Sum32() % (snowflakeMaxNode + 1))
+}
+
+// NextSnowflakeID generate a time ordered int64 id , unique per node
+func NextSnowflakeID() int64 {
+	return _snowflake.next()
+}
+
+func (s *snowflake) next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UnixMilli() - snowflakeEpoch
+	if now > s.lastMs {
+		s.lastMs = now
+		s.seq = 0
+	} else {
+		// same millisecond or clock moved back , keep counting on the last millisecond ,
+		// borrow the next one when the sequence is used up
+		s.seq++
+		if s.seq > snowflakeMaxSeq {
+			s.lastMs++
+			s.seq = 0
+		}
+	}
+	return s.lastMs<<(snowflakeNodeBits+snowflakeSeqBits) | s.node<<snowflakeSeqBits | s.seq
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+)
+
+type order struct {
+	SnowflakePriWithCreateDelAtBase
+	Name string `gorm:"column:name"`
+}
+
+func (*order) TableName() string {
+	return "t_orders"
+}
+
+func TestSnowflakePrimaryKey(t *testing.T) {
+	if err := SetSnowflakeNode(1024); err == nil {
+		t.Fatalf("expect node out of range error")
+	}
+	if err := SetSnowflakeNode(7); err != nil {
+		t.Fatalf("set node err %v", err)
+	}
+	defer func() {
+		_ = SetSnowflakeNode(0)
+	}()
+	if a, b := hostSnowflakeNode("host-a"), hostSnowflakeNode("host-b"); a == b || a != hostSnowflakeNode("host-a") ||
+		a < 0 || a > snowflakeMaxNode || b < 0 || b > snowflakeMaxNode {
+		t.Fatalf("unexpected host nodes %d , %d", a, b)
+	}
+	last := int64(0)
+	for i := 0; i < 10000; i++ {
+		id := NextSnowflakeID()
+		if id <= last {
+			t.Fatalf("id %d not greater than %d", id, last)
+		}
+		if node := id >> snowflakeSeqBits & snowflakeMaxNode; node != 7 {
+			t.Fatalf("expect node 7 , got %d", node)
+		}
+		last = id
+	}
+	orm := newTestORM(t, new(order))
+	var err error
+	o := &order{Name: "o1"}
+	if err = orm.Create(o).Error; err != nil {
+		t.Fatalf("create err %v", err)
+	}
+	if o.ID <= last || o.IdStr == "" {
+		t.Fatalf("unexpected id %d / '%s'", o.ID, o.IdStr)
+	}
+	found := new(order)
+	if err = orm.First(found, o.ID).Error; err != nil || found.IdStr != o.IdStr || found.CreatedAtTs == 0 {
+		t.Fatalf("unexpected found order %+v , %v", found, err)
+	}
+}
@@ -0,0 +1,261 @@
+package db
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// json formats of Timestamp
+const (
+	TimeFormatSeconds = "seconds"
+	TimeFormatMillis  = "millis"
+	TimeFormatRFC3339 = "rfc3339"
+)
+
+var _timeFormat atomic.Pointer[string]
+
+func init() {
+	format := TimeFormatSeconds
+	_timeFormat.Store(&format)
+}
+
+// SetTimeFormat change the package level json format of Timestamp , one of TimeFormatSeconds (default) ,
+// TimeFormatMillis or TimeFormatRFC3339 , read from the timeFormat config of the default datasource
+func SetTimeFormat(format string) error {
+	switch format {
+	case TimeFormatSeconds, TimeFormatMillis, TimeFormatRFC3339:
+	case "":
+		format = TimeFormatSeconds
+	default:
+		return errors.Errorf("unknown time format '%s' , must be one of [%s,%s,%s]", format,
+			TimeFormatSeconds, TimeFormatMillis, TimeFormatRFC3339)
+	}
+	_timeFormat.Store(&format)
+	return nil
+}
+
+// TimeFormat the json format of TimestampOf , chosen per model by the type argument
+type TimeFormat interface {
+	timeFormat() string
+}
+
+type (
+	// DefaultTimeFormat the package level format , see SetTimeFormat
+	DefaultTimeFormat struct{}
+	SecondsTimeFormat struct{}
+	MillisTimeFormat  struct{}
+	RFC3339TimeFormat struct{}
+)
+
+func (DefaultTimeFormat) timeFormat() string { return *_timeFormat.Load() }
+func (SecondsTimeFormat) timeFormat() string { return TimeFormatSeconds }
+func (MillisTimeFormat) timeFormat() string  { return TimeFormatMillis }
+func (RFC3339TimeFormat) timeFormat() string { return TimeFormatRFC3339 }
+
+// TimestampOf a time column marshaled to json in format F when marshaled , so the value is right
+// however the model was built or scanned
+type TimestampOf[F TimeFormat] struct {
+	time.Time
+}
+
+// Timestamp a time column in the package level format
+type Timestamp = TimestampOf[DefaultTimeFormat]
+
+func (this TimestampOf[F]) MarshalJSON() ([]byte, error) {
+	return marshalTime(this.Time, formatOf[F]())
+}
+
+func (this *TimestampOf[F]) UnmarshalJSON(data []byte) error {
+	t, err := unmarshalTime(data, formatOf[F]())
+	if err != nil {
+		return err
+	}
+	this.Time = t
+	return nil
+}
+
+func (this *TimestampOf[F]) Scan(value interface{}) error {
+	t, err := scanTime(value)
+	if err != nil {
+		return err
+	}
+	this.Time = t
+	return nil
+}
+
+func (this TimestampOf[F]) Value() (driver.Value, error) {
+	return this.Time, nil
+}
+
+func (TimestampOf[F]) GormDataType() string {
+	return string(schema.Time)
+}
+
+// DeletedTimestampOf the soft delete column (behaves as gorm.DeletedAt) marshaled in format F ,
+// null when the row is not deleted
+type DeletedTimestampOf[F TimeFormat] struct {
+	gorm.DeletedAt
+}
+
+// DeletedTimestamp the soft delete column in the package level format
+type DeletedTimestamp = DeletedTimestampOf[DefaultTimeFormat]
+
+func (this DeletedTimestampOf[F]) IsZero() bool {
+	return !this.Valid
+}
+
+func (this DeletedTimestampOf[F]) MarshalJSON() ([]byte, error) {
+	if !this.Valid {
+		return []byte("null"), nil
+	}
+	return marshalTime(this.Time, formatOf[F]())
+}
+
+func (this *DeletedTimestampOf[F]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		this.DeletedAt = gorm.DeletedAt{}
+		return nil
+	}
+	t, err := unmarshalTime(data, formatOf[F]())
+	if err != nil {
+		return err
+	}
+	this.DeletedAt = gorm.DeletedAt{Time: t, Valid: true}
+	return nil
+}
+
+func (DeletedTimestampOf[F]) GormDataType() string {
+	return string(schema.Time)
+}
+
+// timeLocator a timestamp column which can be moved into the timezone of a datasource
+type timeLocator interface {
+	setLocation(loc *time.Location)
+}
+
+var _timeLocatorType = reflect.TypeOf((*timeLocator)(nil)).Elem()
+
+func (this *TimestampOf[F]) setLocation(loc *time.Location) {
+	if !this.Time.IsZero() {
+		this.Time = this.Time.In(loc)
+	}
+}
+
+func (this *DeletedTimestampOf[F]) setLocation(loc *time.Location) {
+	if this.Valid {
+		this.Time = this.Time.In(loc)
+	}
+}
+
+// timeLocationPlugin move the timestamp columns (TimestampOf , DeletedTimestampOf) of the rows found
+// into the timezone of the datasource , so TimeFormatRFC3339 renders them in it. drivers return the
+// time in the location of the process or UTC
+type timeLocationPlugin struct {
+	location *time.Location
+}
+
+func (timeLocationPlugin) Name() string {
+	return "kboot:time_location"
+}
+
+func (p timeLocationPlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Query().After("gorm:after_query").
+		Register("kboot:time_location", p.locate)
+}
+
+func (p timeLocationPlugin) locate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || p.location == nil {
+		return
+	}
+	fields := make([]*schema.Field, 0)
+	for _, field := range db.Statement.Schema.Fields {
+		if field.DataType == schema.Time && reflect.PointerTo(field.FieldType).Implements(_timeLocatorType) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	eachModel(db.Statement.ReflectValue, func(v reflect.Value) {
+		for _, field := range fields {
+			if fv := field.ReflectValueOf(db.Statement.Context, v); fv.IsValid() && fv.CanAddr() {
+				fv.Addr().Interface().(timeLocator).setLocation(p.location)
+			}
+		}
+	})
+}
+
+func formatOf[F TimeFormat]() string {
+	var f F
+	return f.timeFormat()
+}
+
+func marshalTime(t time.Time, format string) ([]byte, error) {
+	switch format {
+	case TimeFormatMillis:
+		return strconv.AppendInt(nil, t.UnixMilli(), 10), nil
+	case TimeFormatRFC3339:
+		// in the location of the value , rows read by a datasource are in its timezone (see timeLocationPlugin)
+		return t.MarshalJSON()
+	default:
+		return strconv.AppendInt(nil, t.Unix(), 10), nil
+	}
+}
+
+// unmarshalTime parse a number in format (seconds or millis) or a RFC3339 string
+func unmarshalTime(data []byte, format string) (time.Time, error) {
+	if len(data) > 0 && data[0] == '"' {
+		var t time.Time
+		err := t.UnmarshalJSON(data)
+		return t, err
+	}
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid timestamp %s", data)
+	}
+	if format == TimeFormatMillis {
+		return time.UnixMilli(n), nil
+	}
+	return time.Unix(n, 0), nil
+}
+
+// scanTime a time column value , sqlite may return text
+func scanTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case string:
+		return parseTimeText(v)
+	case []byte:
+		return parseTimeText(string(v))
+	default:
+		return time.Time{}, errors.Errorf("can not scan %T into timestamp", value)
+	}
+}
+
+var _timeTextLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC3339Nano,
+}
+
+func parseTimeText(s string) (time.Time, error) {
+	for _, layout := range _timeTextLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid time '%s'", s)
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type note struct {
+	UuidPrimaryKey
+	CreatedAtOf[DefaultTimeFormat]
+	UpdatedAtOf[DefaultTimeFormat]
+	DeletedAtOf[DefaultTimeFormat]
+	Title string `gorm:"column:title" json:"title"`
+}
+
+func (*note) TableName() string {
+	return "t_notes"
+}
+
+type msNote struct {
+	UuidPrimaryKey
+	CreatedAtOf[MillisTimeFormat]
+	Title string `gorm:"column:title" json:"title"`
+}
+
+func (*msNote) TableName() string {
+	return "t_notes"
+}
+
+func TestTimestampFormat(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	// built in memory , no hook involved
+	n := &note{Title: "n1"}
+	n.CreatedAt.Time = ts
+	raw, _ := json.Marshal(n)
+	if !strings.Contains(string(raw), `"createdAt":1704164645,`) || strings.Contains(string(raw), "deletedAt") {
+		t.Fatalf("unexpected seconds json %s", raw)
+	}
+	if err := SetTimeFormat(TimeFormatMillis); err != nil {
+		t.Fatalf("set format err %v", err)
+	}
+	raw, _ = json.Marshal(n)
+	if !strings.Contains(string(raw), `"createdAt":1704164645006,`) {
+		t.Fatalf("unexpected millis json %s", raw)
+	}
+	_ = SetTimeFormat(TimeFormatRFC3339)
+	defer func() {
+		_ = SetTimeFormat(TimeFormatSeconds)
+	}()
+	cst := time.FixedZone("CST", 8*3600)
+	n.CreatedAt.Time = ts.In(cst)
+	raw, _ = json.Marshal(n)
+	if !strings.Contains(string(raw), `"createdAt":"2024-01-02T11:04:05.006+08:00"`) {
+		t.Fatalf("unexpected rfc3339 json %s", raw)
+	}
+	back := new(note)
+	if err := json.Unmarshal(raw, back); err != nil || !back.CreatedAt.Equal(ts) {
+		t.Fatalf("unexpected unmarshal %v , %v", back.CreatedAt, err)
+	}
+	if SetTimeFormat("nanos") == nil {
+		t.Fatalf("expect unknown format error")
+	}
+
+	// rows found are rendered in the timezone of the datasource
+	orm := newTestORMIn(t, cst, new(note))
+	var err error
+	n = &note{Title: "n2"}
+	if err = orm.Create(n).Error; err != nil || n.CreatedAt.IsZero() || n.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected created note %+v , %v", n, err)
+	}
+	found := new(note)
+	if err = orm.First(found, "id = ?", n.ID).Error; err != nil || found.CreatedAt.Location() != cst {
+		t.Fatalf("expect found note in datasource timezone , got %v , %v", found.CreatedAt, err)
+	}
+	raw, _ = json.Marshal(found)
+	if !strings.Contains(string(raw), `+08:00"`) {
+		t.Fatalf("unexpected rfc3339 json %s", raw)
+	}
+	// scanned without hooks
+	scanned := make([]*msNote, 0)
+	if err = orm.Raw("SELECT * FROM t_notes").Scan(&scanned).Error; err != nil || len(scanned) != 1 {
+		t.Fatalf("scan err %v", err)
+	}
+	raw, _ = json.Marshal(scanned[0])
+	expect := fmt.Sprintf(`"createdAt":%d`, n.CreatedAt.UnixMilli())
+	if !strings.Contains(string(raw), expect) {
+		t.Fatalf("expect %s in %s", expect, raw)
+	}
+	// soft delete keeps working
+	if err = orm.Delete(n).Error; err != nil {
+		t.Fatalf("delete err %v", err)
+	}
+	var count int64
+	orm.Model(new(note)).Count(&count)
+	deleted := new(note)
+	if err = orm.Unscoped().First(deleted, "id = ?", n.ID).Error; err != nil || count != 0 || !deleted.DeletedAt.Valid {
+		t.Fatalf("unexpected soft delete %d , %+v , %v", count, deleted, err)
+	}
+	raw, _ = json.Marshal(deleted)
+	if !strings.Contains(string(raw), `"deletedAt":"`) {
+		t.Fatalf("expect deletedAt in %s", raw)
+	}
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"crypto/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewUuidV7 time ordered uuid (RFC 9562 version 7) , uppercase without dashes like UuidPrimaryKey
+func NewUuidV7() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(strings.ReplaceAll(id.String(), "-", "")), nil
+}
+
+// NewUuidV7Native time ordered uuid in the standard form , for the postgres uuid type
+func NewUuidV7Native() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// crockford base32 alphabet of ulid
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+type ulidGenerator struct {
+	mu     sync.Mutex
+	lastMs int64
+	// entropy 80 random bits , incremented within the same millisecond so ids stay ordered
+	entropy [10]byte
+}
+
+var _ulid = &ulidGenerator{}
+
+// NewUlid time ordered 26 chars ulid , monotonic within the same millisecond
+func NewUlid() (string, error) {
+	return _ulid.next()
+}
+
+func (g *ulidGenerator) next() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := time.Now().UnixMilli()
+	if ms > g.lastMs {
+		g.lastMs = ms
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			return "", err
+		}
+	} else if !g.incEntropy() {
+		// entropy used up or clock moved back , borrow the next millisecond
+		g.lastMs++
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			return "", err
+		}
+	}
+	var b [16]byte
+	for i := 0; i < 6; i++ {
+		b[i] = byte(g.lastMs >> (40 - 8*i))
+	}
+	copy(b[6:], g.entropy[:])
+	return encodeUlid(b), nil
+}
+
+// incEntropy add 1 to entropy , false when it overflows
+func (g *ulidGenerator) incEntropy() bool {
+	for i := len(g.entropy) - 1; i >= 0; i-- {
+		g.entropy[i]++
+		if g.entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeUlid 128 bits to 26 base32 chars , the first char holds the top 3 bits
+func encodeUlid(b [16]byte) string {
+	out := make([]byte, 26)
+	// take 5 bits at a time from the 130 bits value (2 leading zero bits)
+	var acc uint64
+	bits := 2
+	idx := 0
+	for _, v := range b {
+		acc = acc<<8 | uint64(v)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[idx] = ulidAlphabet[(acc>>uint(bits))&0x1f]
+			idx++
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+type event struct {
+	UlidPriWithCreateAtBase
+	Name string `gorm:"column:name"`
+}
+
+func (*event) TableName() string {
+	return "t_events"
+}
+
+func TestTimeOrderedIds(t *testing.T) {
+	ulids := make([]string, 0, 1000)
+	uuids := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		ulid, err := NewUlid()
+		if err != nil || len(ulid) != 26 || strings.Trim(ulid, ulidAlphabet) != "" {
+			t.Fatalf("invalid ulid '%s' , %v", ulid, err)
+		}
+		ulids = append(ulids, ulid)
+		id, err := NewUuidV7()
+		if err != nil || len(id) != 32 || id[12] != '7' {
+			t.Fatalf("invalid uuid v7 '%s' , %v", id, err)
+		}
+		uuids = append(uuids, id)
+	}
+	if !sort.StringsAreSorted(ulids) || !sort.StringsAreSorted(uuids) {
+		t.Fatalf("ids not time ordered")
+	}
+	if native, _ := NewUuidV7Native(); len(native) != 36 {
+		t.Fatalf("invalid native uuid '%s'", native)
+	}
+	if got := encodeUlid([16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Fatalf("unexpected max ulid '%s'", got)
+	}
+	orm := newTestORM(t, new(event))
+	var err error
+	e := &event{Name: "e1"}
+	if err = orm.Create(e).Error; err != nil || len(e.ID) != 26 || e.CreatedAtTs == 0 {
+		t.Fatalf("unexpected created event %+v , %v", e, err)
+	}
+}